@@ -3,11 +3,19 @@ package assets
 import (
 	"bytes"
 	"encoding/hex"
+
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/bin"
 )
 
 var (
-	MDC  = []byte{0x01}
-	AUTH = []byte{0x02} // Users auth-info (User`s public key)
+	MDC    = []byte{0x01}
+	AUTH   = []byte{0x02} // Users auth-info (User`s public key)
+	POA    = []byte{0x03} // Validators set of PoA-consensus
+	INFO   = []byte{0x04} // Metadata of custom assets (address is assetID)
+	SUPPLY = []byte{0x05} // Total supply of custom assets (address is assetID)
+	SEQ    = []byte{0x06} // Sequence numbers of transactions of senders
+	MULTI  = []byte{0x07} // Multisig policies of accounts
 
 	Default = MDC
 )
@@ -25,10 +33,69 @@ const (
 	µCoin int64 = 1000
 )
 
+// customPrefix is first byte of ID of custom asset
+const customPrefix = 0x10
+
+// Info is metadata of custom asset (see: txobj.AssetDef)
+type Info struct {
+	Symbol    string     //
+	Decimals  int        //
+	Issuer    []byte     // address of issuer
+	MaxSupply bignum.Int // 0 - unlimited
+	Mintable  bool       //
+	Burnable  bool       //
+}
+
+// InfoByID returns metadata of custom asset or nil (is set by chain storage)
+var InfoByID = func(asset []byte) *Info {
+	return nil
+}
+
+// CustomID returns ID of custom asset of the issuer
+func CustomID(issuer []byte, symbol string) []byte {
+	return append([]byte{customPrefix}, bin.Hash256(issuer, symbol)[:8]...)
+}
+
+// IsCustom returns true if the asset is custom asset
+func IsCustom(asset []byte) bool {
+	return len(asset) == 9 && asset[0] == customPrefix
+}
+
+func (inf *Info) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		inf.Symbol,
+		inf.Decimals,
+		inf.Issuer,
+		inf.MaxSupply,
+		inf.Mintable,
+		inf.Burnable,
+	)
+}
+
+func (inf *Info) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&inf.Symbol,
+		&inf.Decimals,
+		&inf.Issuer,
+		&inf.MaxSupply,
+		&inf.Mintable,
+		&inf.Burnable,
+	)
+}
+
 func Units(asset []byte) int64 {
 	if IsMDC(asset) {
 		return Coin
 	}
+	if inf := info(asset); inf != nil {
+		u := int64(1)
+		for i := 0; i < inf.Decimals; i++ {
+			u *= 10
+		}
+		return u
+	}
 	return 1
 }
 
@@ -36,6 +103,11 @@ func IsMDC(typ []byte) bool {
 	return len(typ) == 0 || bytes.Equal(typ, MDC)
 }
 
+// IsTransferable returns true for MDC and custom assets (system assets can not be transferred)
+func IsTransferable(asset []byte) bool {
+	return IsMDC(asset) || IsCustom(asset)
+}
+
 func Encode(asset []byte) string {
 	return hex.EncodeToString(asset)
 }
@@ -44,5 +116,15 @@ func String(asset []byte) string {
 	if IsMDC(asset) {
 		return "MDC"
 	}
+	if inf := info(asset); inf != nil {
+		return inf.Symbol
+	}
 	return hex.EncodeToString(asset)
 }
+
+func info(asset []byte) *Info {
+	if IsCustom(asset) {
+		return InfoByID(asset)
+	}
+	return nil
+}
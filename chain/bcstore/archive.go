@@ -0,0 +1,278 @@
+package bcstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/bin"
+)
+
+// Block archive is portable file of blocks that does not depend on layout of db-storage.
+//
+//	archive  := header record* index trailer
+//	header   := bytes( archiveMagic, version, chainID, fromNum, toNum )
+//	record   := varint(recType) bytes(data)
+//	   recBlock:    data = Block.Encode()
+//	   recChecksum: data = sha256 of data of blocks after previous checksum
+//	   recIndex:    data = offsets of block records
+//	trailer  := uint64(offset of index record) archiveMagic
+const (
+	archiveMagic            = "MDCBLOCK"
+	archiveVersion          = 1
+	archiveChecksumInterval = 100 // count of blocks between checksums
+	archiveMaxRecordSize    = 256 << 20
+	archiveTrailerSize      = 8 + len(archiveMagic)
+
+	recBlock    = 1
+	recChecksum = 2
+	recIndex    = 3
+)
+
+var (
+	ErrInvalidArchive  = errors.New("bcstore: invalid block archive")
+	ErrArchiveChecksum = errors.New("bcstore: invalid checksum of block archive")
+)
+
+type archiveHeader struct {
+	Version int
+	ChainID uint64
+	From    uint64
+	To      uint64
+}
+
+func (h *archiveHeader) Encode() []byte {
+	return bin.Encode(archiveMagic, h.Version, h.ChainID, h.From, h.To)
+}
+
+func (h *archiveHeader) Decode(data []byte) error {
+	var magic string
+	if err := bin.Decode(data, &magic, &h.Version, &h.ChainID, &h.From, &h.To); err != nil {
+		return err
+	}
+	if magic != archiveMagic || h.Version != archiveVersion {
+		return ErrInvalidArchive
+	}
+	return nil
+}
+
+// ExportBlocks writes blocks from..to (to=0 - up to last block) to the archive
+func (s *ChainStorage) ExportBlocks(from, to uint64, w io.Writer) error {
+	if from == 0 {
+		from = 1
+	}
+	if last := s.LastBlock().Num; to == 0 || to > last {
+		to = last
+	}
+	if from > to {
+		return ErrBlockNotFound
+	}
+	wr := bin.NewWriter(w)
+	wr.WriteBytes((&archiveHeader{archiveVersion, s.Cfg.ChainID, from, to}).Encode())
+
+	var offsets []uint64
+	sum := sha256.New()
+	writeChecksum := func() {
+		wr.WriteVarInt(recChecksum)
+		wr.WriteBytes(sum.Sum(nil))
+		sum.Reset()
+	}
+	err := s.FetchBlocks(from-1, int64(to-from+1), false, func(block *chain.Block) error {
+		data := block.Encode()
+		offsets = append(offsets, uint64(wr.CntWritten))
+		wr.WriteVarInt(recBlock)
+		wr.WriteBytes(data)
+		sum.Write(data)
+		if len(offsets)%archiveChecksumInterval == 0 {
+			writeChecksum()
+		}
+		return wr.Error()
+	})
+	if err != nil {
+		return err
+	}
+	if len(offsets)%archiveChecksumInterval != 0 {
+		writeChecksum()
+	}
+
+	// index and trailer
+	indexOffset := uint64(wr.CntWritten)
+	wr.WriteVarInt(recIndex)
+	wr.WriteBytes(bin.Encode(offsets))
+	wr.Write(bin.Uint64ToBytes(indexOffset))
+	wr.Write([]byte(archiveMagic))
+	return wr.Error()
+}
+
+// ImportBlocks reads blocks from the archive and puts them to storage with full verification (see PutBlock).
+// Blocks are committed by groups after verification of checksum of the group. Existing blocks are skipped.
+func (s *ChainStorage) ImportBlocks(r io.Reader) error {
+	ar := &archiveReader{r: bin.NewReader(r)}
+	h, err := ar.readHeader()
+	if err != nil {
+		return err
+	}
+	if h.ChainID != s.Cfg.ChainID {
+		return chain.ErrInvalidChainID
+	}
+	var blocks []*chain.Block
+	for {
+		typ, data, err := ar.readRecord()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case recBlock:
+			block := new(chain.Block)
+			if err = block.Decode(data); err != nil {
+				return err
+			}
+			ar.sum.Write(data)
+			if block.Num > s.LastBlock().Num {
+				blocks = append(blocks, block)
+			}
+
+		case recChecksum:
+			if !bytes.Equal(data, ar.sum.Sum(nil)) {
+				return ErrArchiveChecksum
+			}
+			ar.sum.Reset()
+			if err = s.PutBlock(blocks...); err != nil {
+				return err
+			}
+			blocks = nil
+
+		case recIndex:
+			if len(blocks) > 0 { // blocks without checksum
+				return ErrInvalidArchive
+			}
+			return nil
+
+		default:
+			return ErrInvalidArchive
+		}
+	}
+}
+
+type archiveReader struct {
+	r   *bin.Reader
+	sum hash.Hash
+}
+
+func (ar *archiveReader) readHeader() (h *archiveHeader, err error) {
+	data, err := ar.readBytes()
+	if err != nil {
+		return
+	}
+	h = new(archiveHeader)
+	if err = h.Decode(data); err != nil {
+		return nil, err
+	}
+	ar.sum = sha256.New()
+	return
+}
+
+func (ar *archiveReader) readRecord() (typ int, data []byte, err error) {
+	if typ, err = ar.r.ReadVarInt(); err != nil {
+		return
+	}
+	data, err = ar.readBytes()
+	return
+}
+
+func (ar *archiveReader) readBytes() ([]byte, error) {
+	n, err := ar.r.ReadVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > archiveMaxRecordSize {
+		return nil, ErrInvalidArchive
+	}
+	buf := make([]byte, n)
+	_, err = ar.r.Read(buf)
+	return buf, err
+}
+
+// Archive provides random access to blocks of the archive by index
+type Archive struct {
+	ChainID uint64
+	From    uint64 // num of first block
+	To      uint64 // num of last block
+
+	r       io.ReaderAt
+	size    int64
+	offsets []uint64
+}
+
+// OpenArchive reads header and index of the archive
+func OpenArchive(r io.ReaderAt, size int64) (a *Archive, err error) {
+	if size < int64(archiveTrailerSize) {
+		return nil, ErrInvalidArchive
+	}
+	trailer := make([]byte, archiveTrailerSize)
+	if _, err = r.ReadAt(trailer, size-int64(archiveTrailerSize)); err != nil {
+		return
+	}
+	if string(trailer[8:]) != archiveMagic {
+		return nil, ErrInvalidArchive
+	}
+	ar := &archiveReader{r: bin.NewReader(io.NewSectionReader(r, 0, size))}
+	h, err := ar.readHeader()
+	if err != nil {
+		return
+	}
+	indexOffset := int64(bin.BytesToUint64(trailer[:8]))
+	if indexOffset <= 0 || indexOffset >= size {
+		return nil, ErrInvalidArchive
+	}
+	ar = &archiveReader{r: bin.NewReader(io.NewSectionReader(r, indexOffset, size-indexOffset))}
+	typ, data, err := ar.readRecord()
+	if err != nil {
+		return
+	}
+	if typ != recIndex {
+		return nil, ErrInvalidArchive
+	}
+	var offsets []uint64
+	if err = bin.Decode(data, &offsets); err != nil {
+		return
+	}
+	if uint64(len(offsets)) != h.To-h.From+1 {
+		return nil, ErrInvalidArchive
+	}
+	return &Archive{
+		ChainID: h.ChainID,
+		From:    h.From,
+		To:      h.To,
+		r:       r,
+		size:    size,
+		offsets: offsets,
+	}, nil
+}
+
+// Block returns block of the archive by num
+func (a *Archive) Block(num uint64) (*chain.Block, error) {
+	if num < a.From || num > a.To {
+		return nil, ErrBlockNotFound
+	}
+	offset := int64(a.offsets[num-a.From])
+	ar := &archiveReader{r: bin.NewReader(io.NewSectionReader(a.r, offset, a.size-offset))}
+	typ, data, err := ar.readRecord()
+	if err != nil {
+		return nil, err
+	}
+	if typ != recBlock {
+		return nil, ErrInvalidArchive
+	}
+	block := new(chain.Block)
+	if err = block.Decode(data); err != nil {
+		return nil, err
+	}
+	if block.Num != num {
+		return nil, ErrInvalidArchive
+	}
+	return block, nil
+}
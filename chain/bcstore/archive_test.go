@@ -0,0 +1,110 @@
+package bcstore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestArchiveChain returns chain with n blocks
+func newTestArchiveChain(t testing.TB, n int) *ChainStorage {
+	bc := newTestChain(t)
+	for i := 1; i < n; i++ {
+		_, err := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 1)}, masterKey)
+		assert.NoError(t, err)
+	}
+	return bc
+}
+
+func TestChainStorage_ExportBlocks(t *testing.T) {
+	src := newTestArchiveChain(t, 230)
+	defer src.Drop()
+	bc := newTestStorage()
+	defer bc.Drop()
+	buf := bytes.NewBuffer(nil)
+
+	err := src.ExportBlocks(0, 0, buf)
+	assert.NoError(t, err)
+
+	err = bc.ImportBlocks(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 230, bc.LastBlock().Num)
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+	assert.Equal(t, balance(src, cat), balance(bc, cat))
+
+	// existing blocks are skipped
+	err = bc.ImportBlocks(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 230, bc.LastBlock().Num)
+}
+
+func TestOpenArchive(t *testing.T) {
+	src := newTestArchiveChain(t, 20)
+	defer src.Drop()
+	buf := bytes.NewBuffer(nil)
+	err := src.ExportBlocks(5, 15, buf)
+	assert.NoError(t, err)
+
+	a, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 5, a.From)
+	assert.EqualValues(t, 15, a.To)
+	assert.Equal(t, src.Cfg.ChainID, a.ChainID)
+
+	b10, err1 := a.Block(10)
+	b15, err2 := a.Block(15)
+	_, err3 := a.Block(16)
+	_, err4 := OpenArchive(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), int64(buf.Len()-1))
+
+	src10, _ := src.GetBlock(10)
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, src10.Encode(), b10.Encode())
+	assert.EqualValues(t, 15, b15.Num)
+	assert.Equal(t, ErrBlockNotFound, err3)
+	assert.Equal(t, ErrInvalidArchive, err4)
+}
+
+func TestChainStorage_ImportBlocks_corrupted(t *testing.T) {
+	src := newTestArchiveChain(t, 150)
+	defer src.Drop()
+	buf := bytes.NewBuffer(nil)
+	err := src.ExportBlocks(0, 0, buf)
+	assert.NoError(t, err)
+	a, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	// corrupt block#120
+	data := buf.Bytes()
+	data[a.offsets[119]+20]++
+
+	bc := newTestStorage()
+	defer bc.Drop()
+	err = bc.ImportBlocks(bytes.NewReader(data))
+
+	assert.Error(t, err)
+	assert.EqualValues(t, 100, bc.LastBlock().Num) // first group of blocks is imported
+}
+
+func TestChainStorage_ImportBlocks_untrustedSource(t *testing.T) {
+	src := newTestArchiveChain(t, 10)
+	defer src.Drop()
+	buf := bytes.NewBuffer(nil)
+	err := src.ExportBlocks(0, 0, buf)
+	assert.NoError(t, err)
+
+	// chain with another master key
+	cfg := newTestConfig()
+	cfg.MasterKey = crypto.NewPrivateKey().PublicKey().String()
+	bc := NewChainStorage(src.Dir+"-import", cfg)
+	defer bc.Drop()
+
+	err = bc.ImportBlocks(bytes.NewReader(buf.Bytes()))
+
+	assert.True(t, errors.Is(err, chain.ErrInvalidMinerKey), err)
+	assert.EqualValues(t, 0, bc.LastBlock().Num)
+}
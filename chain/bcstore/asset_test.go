@@ -0,0 +1,100 @@
+package bcstore
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/stretchr/testify/assert"
+)
+
+func assetBalance(bc *ChainStorage, asset, addr []byte) int64 {
+	return bc.State().Get(asset, addr).Int64()
+}
+
+func putTx(t *testing.T, bc *ChainStorage, tx *chain.Transaction) bool {
+	block, err := bc.PutNewBlock([]*chain.Transaction{tx}, masterKey)
+	assert.NoError(t, err)
+	return block != nil
+}
+
+func TestAssetDef(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	asset := assets.CustomID(alice, "TOKEN")
+
+	ok := putTx(t, bc, txobj.NewAssetDef(bc, nil, aliceKey, "TOKEN", 2, bignum.NewInt(1000), bignum.NewInt(5000), true, true))
+
+	assert.True(t, ok)
+	assert.EqualValues(t, 1000, assetBalance(bc, asset, alice))
+	assert.EqualValues(t, 1000, bc.State().AssetSupply(asset).Int64())
+	assert.Equal(t, "TOKEN", assets.String(asset))
+	assert.EqualValues(t, 100, assets.Units(asset))
+	assert.Equal(t, alice, bc.AssetInfo(asset).Issuer)
+
+	// transfer of custom asset
+	ok = putTx(t, bc, txobj.NewSimpleTransfer(bc, nil, aliceKey, asset, bignum.NewInt(300), 0, bob, 0, "", 0))
+	assert.True(t, ok)
+	assert.EqualValues(t, 700, assetBalance(bc, asset, alice))
+	assert.EqualValues(t, 300, assetBalance(bc, asset, bob))
+
+	// asset can not be redefined
+	ok = putTx(t, bc, txobj.NewAssetDef(bc, nil, aliceKey, "TOKEN", 0, bignum.NewInt(1), bignum.Int{}, false, false))
+	assert.False(t, ok)
+	assert.EqualValues(t, 100, assets.Units(asset))
+
+	// unknown asset
+	assert.EqualValues(t, 1, assets.Units(assets.CustomID(bob, "TOKEN")))
+}
+
+func TestAssetDef_invalid(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	for _, tx := range []*chain.Transaction{
+		txobj.NewAssetDef(bc, nil, aliceKey, "MDC", 0, bignum.NewInt(1), bignum.Int{}, false, false),
+		txobj.NewAssetDef(bc, nil, aliceKey, "token", 0, bignum.NewInt(1), bignum.Int{}, false, false),
+		txobj.NewAssetDef(bc, nil, aliceKey, "TOKEN", 19, bignum.NewInt(1), bignum.Int{}, false, false),
+		txobj.NewAssetDef(bc, nil, aliceKey, "TOKEN", 0, bignum.NewInt(10), bignum.NewInt(5), false, false),
+	} {
+		assert.Error(t, tx.Verify())
+	}
+}
+
+func TestMint(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	asset := assets.CustomID(alice, "TOKEN")
+	putTx(t, bc, txobj.NewAssetDef(bc, nil, aliceKey, "TOKEN", 0, bignum.NewInt(1000), bignum.NewInt(5000), true, false))
+
+	okIssuer := putTx(t, bc, txobj.NewMint(bc, nil, aliceKey, asset, bob, bignum.NewInt(3000), 0))
+	okNotIssuer := putTx(t, bc, txobj.NewMint(bc, nil, bobKey, asset, bob, bignum.NewInt(1), 0))
+	okMaxSupply := putTx(t, bc, txobj.NewMint(bc, nil, aliceKey, asset, bob, bignum.NewInt(1001), 0))
+	okNotBurnable := putTx(t, bc, txobj.NewBurn(bc, nil, aliceKey, asset, bignum.NewInt(1), 0))
+
+	assert.True(t, okIssuer)
+	assert.False(t, okNotIssuer)
+	assert.False(t, okMaxSupply)
+	assert.False(t, okNotBurnable)
+	assert.EqualValues(t, 3000, assetBalance(bc, asset, bob))
+	assert.EqualValues(t, 4000, bc.State().AssetSupply(asset).Int64())
+}
+
+func TestBurn(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	asset := assets.CustomID(alice, "TOKEN")
+	putTx(t, bc, txobj.NewAssetDef(bc, nil, aliceKey, "TOKEN", 0, bignum.NewInt(1000), bignum.Int{}, false, true))
+
+	okIssuer := putTx(t, bc, txobj.NewBurn(bc, nil, aliceKey, asset, bignum.NewInt(400), 0))
+	okNotEnough := putTx(t, bc, txobj.NewBurn(bc, nil, aliceKey, asset, bignum.NewInt(601), 0))
+	okNotMintable := putTx(t, bc, txobj.NewMint(bc, nil, aliceKey, asset, bob, bignum.NewInt(1), 0))
+
+	assert.True(t, okIssuer)
+	assert.False(t, okNotEnough)
+	assert.False(t, okNotMintable)
+	assert.EqualValues(t, 600, assetBalance(bc, asset, alice))
+	assert.EqualValues(t, 600, bc.State().AssetSupply(asset).Int64())
+}
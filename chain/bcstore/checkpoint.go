@@ -0,0 +1,56 @@
+package bcstore
+
+import (
+	"bytes"
+
+	"github.com/mediacoin-pro/core/chain"
+)
+
+// PutCheckpointHeaders verifies headers of the next blocks of local chain (header-first sync).
+// Headers linked by hashes to checkpoint are trusted, so transactions of their blocks are not executed by PutBlock.
+// Headers can be put by batches; headers after the last checkpoint are ignored.
+func (s *ChainStorage) PutCheckpointHeaders(headers ...*chain.BlockHeader) error {
+	s.mxW.Lock()
+	defer s.mxW.Unlock()
+
+	if last := s.LastBlockHeader(); s.cpHead == nil || s.cpHead.Num < last.Num {
+		s.cpHead, s.cpHashes = last, nil
+	}
+	for _, h := range headers {
+		if h.Num <= s.cpHead.Num {
+			continue
+		}
+		if !s.Cfg.IsCheckpointed(h.Num) {
+			break
+		}
+		if err := h.VerifyHeader(s.cpHead, s.Cfg); err != nil {
+			s.cpHead, s.cpHashes = nil, nil
+			return err
+		}
+		s.cpHead = h
+		s.cpHashes = append(s.cpHashes, h.Hash())
+		if s.Cfg.CheckpointHash(h.Num) != nil { // headers are linked to checkpoint
+			for i, hash := range s.cpHashes {
+				s.trusted[h.Num-uint64(len(s.cpHashes)-1-i)] = hash
+			}
+			s.cpHashes = nil
+		}
+	}
+	return nil
+}
+
+// trustedBlocks returns count of the first blocks which are linked to checkpoint
+// by the trusted headers (see: PutCheckpointHeaders) or by the next blocks of the batch
+func (s *ChainStorage) trustedBlocks(blocks []*chain.Block) (n int) {
+	for i, b := range blocks {
+		if !s.Cfg.IsCheckpointed(b.Num) {
+			break
+		}
+		if s.Cfg.CheckpointHash(b.Num) != nil { // hashes of the previous blocks are verified by verifyBlocks
+			n = i + 1
+		} else if n == i && bytes.Equal(s.trusted[b.Num], b.Hash()) {
+			n = i + 1
+		}
+	}
+	return
+}
@@ -0,0 +1,141 @@
+package bcstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCheckpointStorage(verifyLevel int, checkpoints ...chain.Checkpoint) *ChainStorage {
+	cfg := newTestConfig()
+	cfg.VerifyTxsLevel = verifyLevel
+	cfg.Checkpoints = checkpoints
+	return NewChainStorage(fmt.Sprintf("%s/test-bcstore-%x.db", os.TempDir(), rand.Int()), cfg)
+}
+
+func checkpoint(bc *ChainStorage, num uint64) chain.Checkpoint {
+	h, _ := bc.BlockHeader(num)
+	return chain.Checkpoint{Num: num, Hash: h.Hash()}
+}
+
+func TestChainStorage_PutBlock_checkpoints(t *testing.T) {
+	src := newTestArchiveChain(t, 30)
+	defer src.Drop()
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, src.ExportBlocks(0, 0, buf))
+
+	bc := newTestCheckpointStorage(chain.VerifyTxLevel1, checkpoint(src, 10), checkpoint(src, 20))
+	defer bc.Drop()
+
+	err := bc.ImportBlocks(bytes.NewReader(buf.Bytes()))
+
+	assert.NoError(t, err)
+	assert.True(t, bc.Cfg.IsCheckpointed(20))
+	assert.False(t, bc.Cfg.IsCheckpointed(21))
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+	assert.Equal(t, balance(src, alice), balance(bc, alice))
+	assert.Equal(t, balance(src, cat), balance(bc, cat))
+}
+
+func TestChainStorage_PutBlock_conflictingCheckpoint(t *testing.T) {
+	src := newTestArchiveChain(t, 20)
+	defer src.Drop()
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, src.ExportBlocks(0, 0, buf))
+
+	for _, level := range []int{chain.VerifyTxLevel1, chain.VerifyTxLevel2} {
+		cp := checkpoint(src, 15)
+		cp.Hash = append([]byte{}, cp.Hash...)
+		cp.Hash[0]++
+		bc := newTestCheckpointStorage(level, cp)
+
+		err := bc.ImportBlocks(bytes.NewReader(buf.Bytes()))
+
+		assert.True(t, errors.Is(err, chain.ErrCheckpointMismatch), err)
+		assert.EqualValues(t, 0, bc.LastBlock().Num)
+		bc.Drop()
+	}
+}
+
+func TestChainStorage_PutCheckpointHeaders(t *testing.T) {
+	src := newTestArchiveChain(t, 20)
+	defer src.Drop()
+	var blocks []*chain.Block
+	var headers []*chain.BlockHeader
+	for num := uint64(1); num <= 20; num++ {
+		b, err := src.GetBlock(num)
+		assert.NoError(t, err)
+		blocks = append(blocks, b)
+		headers = append(headers, b.BlockHeader)
+	}
+	// transactions of src-chain are not valid by the config (without fee)
+	bc := newTestCheckpointStorage(chain.VerifyTxLevel1, checkpoint(src, 20))
+	defer bc.Drop()
+	bc.Cfg.FeeHeight = 1
+	bc.Cfg.FeePerByte = 1000
+
+	// blocks are not linked to checkpoint yet
+	err0 := bc.PutBlock(blocks[:10]...)
+	err1 := bc.PutCheckpointHeaders(headers[:10]...)
+	err2 := bc.PutBlock(blocks[:10]...)
+
+	assert.True(t, errors.Is(err0, txobj.ErrTxLowFee), err0)
+	assert.NoError(t, err1)
+	assert.True(t, errors.Is(err2, txobj.ErrTxLowFee), err2)
+	assert.EqualValues(t, 0, bc.LastBlock().Num)
+
+	// headers are linked to checkpoint
+	err3 := bc.PutCheckpointHeaders(headers[10:]...)
+	err4 := bc.PutBlock(blocks[:10]...)
+	err5 := bc.PutBlock(blocks[10:]...)
+
+	assert.NoError(t, err3)
+	assert.NoError(t, err4)
+	assert.NoError(t, err5)
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+	assert.Equal(t, balance(src, cat), balance(bc, cat))
+}
+
+func TestChainStorage_PutCheckpointHeaders_conflictingCheckpoint(t *testing.T) {
+	src := newTestArchiveChain(t, 10)
+	defer src.Drop()
+	var headers []*chain.BlockHeader
+	for num := uint64(1); num <= 10; num++ {
+		h, _ := src.BlockHeader(num)
+		headers = append(headers, h)
+	}
+	cp := checkpoint(src, 10)
+	cp.Hash = append([]byte{}, cp.Hash...)
+	cp.Hash[0]++
+	bc := newTestCheckpointStorage(chain.VerifyTxLevel1, cp)
+	defer bc.Drop()
+
+	err := bc.PutCheckpointHeaders(headers...)
+
+	assert.True(t, errors.Is(err, chain.ErrCheckpointMismatch), err)
+	assert.Empty(t, bc.trusted)
+}
+
+func BenchmarkChainStorage_ImportBlocks(b *testing.B) {
+	src := newTestArchiveChain(b, 300)
+	defer src.Drop()
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(b, src.ExportBlocks(0, 0, buf))
+
+	for _, level := range []int{chain.VerifyTxLevel1, chain.VerifyTxLevel2} {
+		b.Run(fmt.Sprintf("level%d", level), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bc := newTestCheckpointStorage(level, checkpoint(src, 300))
+				assert.NoError(b, bc.ImportBlocks(bytes.NewReader(buf.Bytes())))
+				bc.Drop()
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package bcstore
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransfer_encryptedComments(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	tx := txobj.NewTransfer(bc, nil, aliceKey, []*txobj.TransferOutput{
+		(&txobj.TransferOutput{Asset: assets.MDC, Amount: coins(1), ToChainID: bc.Cfg.ChainID}).SetComment(bobKey.PublicKey(), "for Bob"),
+		(&txobj.TransferOutput{Asset: assets.MDC, Amount: coins(1), ToChainID: bc.Cfg.ChainID}).SetComment(catKey.PublicKey(), "for Cat"),
+	}, "note of Alice", 0)
+	assert.True(t, putTx(t, bc, tx))
+
+	// decode stored transaction
+	tx, err := bc.TransactionByID(tx.ID())
+	assert.NoError(t, err)
+	tr := tx.TxObject().(*txobj.Transfer)
+	assert.NotContains(t, string(tx.Data), "Alice")
+	assert.NotContains(t, string(tx.Data), "Bob")
+
+	// sender comment
+	_, err = tr.DecryptComment(bobKey)
+	assert.Equal(t, crypto.ErrDecrypt, err)
+	comment, err := tr.DecryptComment(aliceKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "note of Alice", comment)
+
+	// recipient comments
+	_, err = tr.Outs[0].DecryptComment(catKey)
+	assert.Equal(t, crypto.ErrDecrypt, err)
+	comment, err = tr.Outs[0].DecryptComment(bobKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "for Bob", comment)
+	comment, err = tr.Outs[1].DecryptComment(catKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "for Cat", comment)
+
+	// sender decrypts comment of decoded output by key of recipient
+	var decoded chain.Transaction
+	assert.NoError(t, decoded.Decode(tx.Encode()))
+	out := decoded.TxObject().(*txobj.Transfer).Outs[0]
+	_, err = out.DecryptComment(aliceKey)
+	assert.Equal(t, crypto.ErrDecrypt, err)
+	assert.Equal(t, txobj.ErrTxIncorrectAddress, out.SetRecipientKey(catKey.PublicKey()))
+	assert.NoError(t, out.SetRecipientKey(bobKey.PublicKey()))
+	comment, err = out.DecryptComment(aliceKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "for Bob", comment)
+}
+
+func TestTransfer_encryptedComments_counterTransfer(t *testing.T) {
+	// transfers A->B and B->A with the same nonce are encrypted by the same shared key
+	out := func(to *crypto.PrivateKey) *txobj.TransferOutput {
+		return (&txobj.TransferOutput{Asset: assets.MDC, Amount: coins(1), ToChainID: chain.DefaultConfig.ChainID}).SetComment(to.PublicKey(), "message")
+	}
+	tx1 := txobj.NewTransfer(nil, nil, aliceKey, []*txobj.TransferOutput{out(bobKey)}, "", 1)
+	tx2 := txobj.NewTransfer(nil, nil, bobKey, []*txobj.TransferOutput{out(aliceKey)}, "", 1)
+	out1 := tx1.TxObject().(*txobj.Transfer).Outs[0]
+	out2 := tx2.TxObject().(*txobj.Transfer).Outs[0]
+
+	assert.NotEqual(t, out1.Comment, out2.Comment)
+
+	// comment of one transfer can not be moved to another
+	out2.Comment = out1.Comment
+	_, err := out2.DecryptComment(aliceKey)
+	assert.Equal(t, crypto.ErrDecrypt, err)
+}
+
+func TestTransfer_MarshalJSON_decrypted(t *testing.T) {
+	tx := txobj.NewTransfer(nil, nil, aliceKey, []*txobj.TransferOutput{
+		(&txobj.TransferOutput{Asset: assets.MDC, Amount: coins(1), ToChainID: chain.DefaultConfig.ChainID}).SetComment(bobKey.PublicKey(), "for Bob"),
+	}, "note of Alice", 0)
+
+	var tx1 chain.Transaction
+	assert.NoError(t, tx1.Decode(tx.Encode()))
+	tr := tx1.TxObject().(*txobj.Transfer)
+	data0, _ := json.Marshal(tr)
+	tr.DecryptData(bobKey)
+	data1, _ := json.Marshal(tr)
+
+	assert.NotContains(t, string(data0), "for Bob")
+	assert.Contains(t, string(data1), `"comment":"for Bob"`)
+	assert.NotContains(t, string(data1), "note of Alice")
+}
+
+func TestTransfer_encryptedComments_maxSize(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	comment := strings.Repeat("x", 200)
+	tx := txobj.NewTransfer(bc, nil, aliceKey, []*txobj.TransferOutput{
+		(&txobj.TransferOutput{Asset: assets.MDC, Amount: coins(1), ToChainID: bc.Cfg.ChainID}).SetComment(bobKey.PublicKey(), comment),
+	}, comment, 0)
+	assert.NoError(t, tx.PreVerify())
+	assert.True(t, putTx(t, bc, tx))
+
+	// too long comment
+	tx = txobj.NewTransfer(bc, nil, aliceKey, []*txobj.TransferOutput{
+		(&txobj.TransferOutput{Asset: assets.MDC, Amount: coins(1), ToChainID: bc.Cfg.ChainID}).SetComment(bobKey.PublicKey(), comment+"x"),
+	}, "", 0)
+	assert.Equal(t, txobj.ErrTxLongComment, tx.PreVerify())
+}
@@ -0,0 +1,341 @@
+package bcstore
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/gosync"
+	"github.com/mediacoin-pro/core/common/safe"
+)
+
+// EventType is bit-mask of chain event types
+type EventType int
+
+const (
+	EventBlock       EventType = 1 << iota // block has been committed
+	EventTx                                // transaction has been committed
+	EventRevertBlock                       // block has been reverted (by rollback or reorg)
+
+	EventAll = EventBlock | EventTx | EventRevertBlock
+)
+
+type Event struct {
+	Type  EventType
+	Block *chain.BlockHeader // block of the event
+	Tx    *chain.Transaction // transaction (for EventTx only)
+}
+
+// BackpressurePolicy defines behavior of subscription when buffer of subscriber is full
+type BackpressurePolicy int
+
+const (
+	PolicyBlock      BackpressurePolicy = iota // wait for subscriber (delivery of the next events is delayed)
+	PolicyDrop                                 // drop event
+	PolicyDisconnect                           // close subscription with ErrSlowSubscriber
+)
+
+const defaultEventsBufferSize = 1000
+
+var (
+	ErrSlowSubscriber = errors.New("bcstore: subscriber is too slow")
+)
+
+// EventFilter filters events of subscription. Empty fields match any value.
+type EventFilter struct {
+	Types     EventType // event types (EventAll by default)
+	TxTypes   []int     // types of transactions
+	Assets    [][]byte  // assets of changed state
+	Addresses [][]byte  // addresses of changed state
+	Memos     []uint64  // memo of changed state
+	Senders   [][]byte  // addresses of transaction senders
+}
+
+type SubscribeOptions struct {
+	Filter     EventFilter
+	BufferSize int // size of events channel (1000 by default)
+	Policy     BackpressurePolicy
+	FromBlock  uint64 // replay events starting from the block
+	FromTxUID  uint64 // replay events after the transaction (see Transaction.TxUID())
+}
+
+// Subscription delivers chain events after commit of blocks.
+// Channel C is closed after Unsubscribe or when the subscription is disconnected.
+type Subscription struct {
+	C <-chan *Event
+
+	bc      *ChainStorage
+	opts    SubscribeOptions
+	c       chan *Event
+	notify  chan struct{}
+	stop    gosync.Trigger
+	done    gosync.Trigger
+	dropped int64
+
+	mx      sync.Mutex
+	next    uint64               // txUID of next event
+	gen     int                  // generation of cursor; is incremented by reverts
+	reverts []*chain.BlockHeader // reverted blocks to notify
+	err     error
+}
+
+// Subscribe creates new subscription on chain events
+func (s *ChainStorage) Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultEventsBufferSize
+	}
+	if opts.Filter.Types == 0 {
+		opts.Filter.Types = EventAll
+	}
+	sub := &Subscription{
+		bc:     s,
+		opts:   opts,
+		c:      make(chan *Event, opts.BufferSize),
+		notify: make(chan struct{}, 1),
+		stop:   gosync.NewTrigger(),
+		done:   gosync.NewTrigger(),
+	}
+	sub.C = sub.c
+
+	s.mxW.Lock() // cursor has to be set before next commit
+	defer s.mxW.Unlock()
+
+	switch {
+	case opts.FromTxUID != 0:
+		sub.next = opts.FromTxUID + 1
+	case opts.FromBlock != 0:
+		sub.next = encodeTxUID(opts.FromBlock, 0)
+	default:
+		sub.next = encodeTxUID(s.LastBlock().Num+1, 0)
+	}
+
+	s.mxSubs.Lock()
+	if s.subs == nil {
+		s.subs = map[*Subscription]struct{}{}
+	}
+	s.subs[sub] = struct{}{}
+	s.mxSubs.Unlock()
+
+	go sub.run()
+	return sub
+}
+
+// Unsubscribe stops delivery of events and closes channel C
+func (sub *Subscription) Unsubscribe() {
+	sub.stop.Trigger()
+	sub.done.Wait()
+}
+
+// Err returns the reason of closing of the subscription
+func (sub *Subscription) Err() error {
+	sub.mx.Lock()
+	defer sub.mx.Unlock()
+	return sub.err
+}
+
+// Dropped returns count of dropped events (for PolicyDrop)
+func (sub *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&sub.dropped)
+}
+
+func (s *ChainStorage) notifySubscribers() {
+	s.mxSubs.Lock()
+	defer s.mxSubs.Unlock()
+	for sub := range s.subs {
+		sub.wakeUp()
+	}
+}
+
+// revertSubscribers moves cursors of subscriptions back to first reverted block
+func (s *ChainStorage) revertSubscribers(reverted []*chain.Block) {
+	if len(reverted) == 0 {
+		return
+	}
+	s.mxSubs.Lock()
+	defer s.mxSubs.Unlock()
+	for sub := range s.subs {
+		sub.revert(reverted)
+		sub.wakeUp()
+	}
+}
+
+func (s *ChainStorage) removeSubscription(sub *Subscription) {
+	s.mxSubs.Lock()
+	defer s.mxSubs.Unlock()
+	delete(s.subs, sub)
+}
+
+func (sub *Subscription) wakeUp() {
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *Subscription) revert(reverted []*chain.Block) {
+	sub.mx.Lock()
+	defer sub.mx.Unlock()
+
+	nextNum, nextIdx := decodeTxUID(sub.next)
+	for i := len(reverted) - 1; i >= 0; i-- {
+		if h := reverted[i].BlockHeader; h.Num < nextNum || h.Num == nextNum && nextIdx > 0 { // events of the block have been delivered
+			sub.reverts = append(sub.reverts, h)
+		}
+	}
+	if first := encodeTxUID(reverted[0].Num, 0); sub.next > first {
+		sub.next = first
+	}
+	sub.gen++
+}
+
+func (sub *Subscription) run() {
+	defer sub.done.Trigger()
+	defer close(sub.c)
+	defer sub.bc.removeSubscription(sub)
+	defer safe.RecoverAndReport()
+
+	for sub.deliver() {
+		select {
+		case <-sub.notify:
+		case <-sub.stop:
+			return
+		}
+	}
+}
+
+// deliver sends all available events to subscriber. It returns false when the subscription has to be closed.
+func (sub *Subscription) deliver() bool {
+	for {
+		sub.mx.Lock()
+		next, gen, reverts := sub.next, sub.gen, sub.reverts
+		sub.reverts = nil
+		sub.mx.Unlock()
+
+		for _, h := range reverts {
+			if sub.opts.Filter.Types&EventRevertBlock != 0 && !sub.send(&Event{Type: EventRevertBlock, Block: h}) {
+				return false
+			}
+		}
+		num, idx := decodeTxUID(next)
+		if num > sub.bc.LastBlock().Num {
+			return true // wait for new blocks
+		}
+		block, err := sub.bc.GetBlock(num)
+		if err == ErrBlockNotFound {
+			return true // block has been reverted; wait for notification
+		} else if err != nil {
+			sub.close(err)
+			return false
+		}
+		if idx == 0 && sub.opts.Filter.Types&EventBlock != 0 {
+			if !sub.send(&Event{Type: EventBlock, Block: block.BlockHeader}) {
+				return false
+			}
+		}
+		for ; idx < len(block.Txs); idx++ {
+			if tx := block.Txs[idx]; sub.opts.Filter.Types&EventTx != 0 && sub.opts.Filter.matchTx(tx) {
+				if !sub.send(&Event{Type: EventTx, Block: block.BlockHeader, Tx: tx}) {
+					return false
+				}
+			}
+			if !sub.moveCursor(gen, encodeTxUID(num, idx+1)) {
+				break // cursor has been moved by revert
+			}
+		}
+		sub.moveCursor(gen, encodeTxUID(num+1, 0))
+	}
+}
+
+func (sub *Subscription) moveCursor(gen int, next uint64) bool {
+	sub.mx.Lock()
+	defer sub.mx.Unlock()
+	if sub.gen != gen {
+		return false
+	}
+	sub.next = next
+	return true
+}
+
+func (sub *Subscription) send(e *Event) bool {
+	switch sub.opts.Policy {
+	case PolicyDrop:
+		select {
+		case sub.c <- e:
+		case <-sub.stop:
+			return false
+		default:
+			atomic.AddInt64(&sub.dropped, 1)
+		}
+	case PolicyDisconnect:
+		select {
+		case sub.c <- e:
+		case <-sub.stop:
+			return false
+		default:
+			sub.close(ErrSlowSubscriber)
+			return false
+		}
+	default:
+		select {
+		case sub.c <- e:
+		case <-sub.stop:
+			return false
+		}
+	}
+	return true
+}
+
+func (sub *Subscription) close(err error) {
+	sub.mx.Lock()
+	defer sub.mx.Unlock()
+	sub.err = err
+}
+
+func (f *EventFilter) matchTx(tx *chain.Transaction) bool {
+	if len(f.TxTypes) > 0 && !containsInt(f.TxTypes, tx.Type) {
+		return false
+	}
+	if len(f.Senders) > 0 && !containsBytes(f.Senders, tx.SenderAddress()) {
+		return false
+	}
+	if len(f.Assets) == 0 && len(f.Addresses) == 0 && len(f.Memos) == 0 {
+		return true
+	}
+	for _, v := range tx.StateUpdates {
+		if (len(f.Assets) == 0 || containsBytes(f.Assets, v.Asset)) &&
+			(len(f.Addresses) == 0 || containsBytes(f.Addresses, v.Address)) &&
+			(len(f.Memos) == 0 || containsUint64(f.Memos, v.Memo)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(vv []int, v int) bool {
+	for _, x := range vv {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUint64(vv []uint64, v uint64) bool {
+	for _, x := range vv {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsBytes(vv [][]byte, v []byte) bool {
+	for _, x := range vv {
+		if bytes.Equal(x, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package bcstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainStorage_Subscribe(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	sub := bc.Subscribe(SubscribeOptions{})
+	defer sub.Unsubscribe()
+
+	block, _ := bc.PutNewBlock([]*chain.Transaction{
+		newTransfer(bc, aliceKey, cat, 10),
+		newTransfer(bc, bobKey, cat, 20),
+	}, masterKey)
+
+	events := readEvents(sub, 3)
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, EventBlock, events[0].Type)
+	assert.Equal(t, block.Hash(), events[0].Block.Hash())
+	assert.Equal(t, EventTx, events[1].Type)
+	assert.Equal(t, block.Txs[0].ID(), events[1].Tx.ID())
+	assert.Equal(t, EventTx, events[2].Type)
+	assert.Equal(t, block.Txs[1].ID(), events[2].Tx.ID())
+}
+
+func TestChainStorage_Subscribe_filter(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	sub := bc.Subscribe(SubscribeOptions{
+		FromBlock: 1,
+		Filter: EventFilter{
+			Types:     EventTx,
+			TxTypes:   []int{model.TxTransfer},
+			Assets:    [][]byte{assets.MDC},
+			Addresses: [][]byte{cat},
+		},
+	})
+	defer sub.Unsubscribe()
+
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, bob, 10)}, masterKey)
+	block, _ := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, bobKey, cat, 20)}, masterKey)
+
+	events := readEvents(sub, 2)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, block.Txs[0].ID(), events[0].Tx.ID())
+}
+
+func TestChainStorage_Subscribe_replay(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 10)}, masterKey)
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 20)}, masterKey)
+	tx, _ := bc.GetTransaction(2, 0)
+
+	sub := bc.Subscribe(SubscribeOptions{FromTxUID: tx.TxUID(), Filter: EventFilter{Types: EventTx}})
+	defer sub.Unsubscribe()
+
+	events := readEvents(sub, 2)
+	assert.Equal(t, 1, len(events))
+	assert.EqualValues(t, 3, events[0].Tx.BlockNum())
+}
+
+func TestChainStorage_Subscribe_revert(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	sub := bc.Subscribe(SubscribeOptions{Filter: EventFilter{Types: EventBlock | EventRevertBlock}})
+	defer sub.Unsubscribe()
+
+	block, _ := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 10)}, masterKey)
+	readEvents(sub, 1)
+	bc.RollbackTo(1)
+
+	events := readEvents(sub, 1)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, EventRevertBlock, events[0].Type)
+	assert.Equal(t, block.Hash(), events[0].Block.Hash())
+}
+
+func TestChainStorage_Subscribe_policyDisconnect(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	sub := bc.Subscribe(SubscribeOptions{FromBlock: 1, BufferSize: 1, Policy: PolicyDisconnect})
+
+	sub.done.Wait()
+	_, ok := <-sub.C
+	assert.Equal(t, ErrSlowSubscriber, sub.Err())
+	assert.True(t, ok) // buffered event
+	_, ok = <-sub.C
+	assert.False(t, ok)
+}
+
+func TestChainStorage_Subscribe_policyDrop(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 10)}, masterKey)
+
+	sub := bc.Subscribe(SubscribeOptions{FromBlock: 1, BufferSize: 1, Policy: PolicyDrop})
+	defer sub.Unsubscribe()
+	for sub.Dropped() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.EqualValues(t, 3, sub.Dropped())
+	assert.Nil(t, sub.Err())
+}
+
+func TestSubscription_Unsubscribe(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	sub := bc.Subscribe(SubscribeOptions{})
+	sub.Unsubscribe()
+
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	assert.Equal(t, 0, len(bc.subs))
+}
+
+func readEvents(sub *Subscription, n int) (events []*Event) {
+	timeout := time.After(100 * time.Millisecond)
+	for len(events) < n {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			events = append(events, e)
+		case <-timeout:
+			return
+		}
+	}
+	return
+}
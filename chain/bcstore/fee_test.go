@@ -0,0 +1,97 @@
+package bcstore
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTransferWithFee(bc chain.BCContext, from *crypto.PrivateKey, to []byte, amount int64, fee bignum.Int) *chain.Transaction {
+	return txobj.NewTransferWithFee(bc, from.PublicKey(), from, []*txobj.TransferOutput{{
+		Asset:     assets.MDC,
+		Amount:    coins(amount),
+		To:        to,
+		ToChainID: bc.Config().ChainID,
+	}}, "", fee, 0)
+}
+
+func TestTransfer_Fee_toMiner(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	miner := masterKey.PublicKey().Address()
+
+	ok := putTx(t, bc, newTransferWithFee(bc, aliceKey, cat, 10, coins(2)))
+
+	assert.True(t, ok)
+	assert.EqualValues(t, 988, balance(bc, alice))
+	assert.EqualValues(t, 10, balance(bc, cat))
+	assert.EqualValues(t, 2, stateBalance(bc, miner))
+	assert.Equal(t, coins(2), bc.Totals().Fees)
+}
+
+func TestTransfer_Fee_burn(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.Cfg.BurnFees = true
+
+	ok := putTx(t, bc, newTransferWithFee(bc, aliceKey, cat, 10, coins(2)))
+
+	assert.True(t, ok)
+	assert.EqualValues(t, 988, balance(bc, alice))
+	assert.EqualValues(t, 10, balance(bc, cat))
+	assert.EqualValues(t, 0, stateBalance(bc, masterKey.PublicKey().Address()))
+	assert.Equal(t, coins(2), bc.Totals().Fees)
+}
+
+func TestTransfer_Fee_min(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.Cfg.FeeHeight = 2
+	bc.Cfg.FeePerByte = 1000
+	bc.Cfg.FeePerOutput = 1e6
+
+	tx := newTransfer(bc, aliceKey, cat, 10)
+	minFee := bc.Cfg.MinTxFee(2, len(tx.Data), 1)
+
+	err1 := bc.PublishTx(tx)
+	err2 := bc.PublishTx(newTransferWithFee(bc, aliceKey, cat, 10, minFee.AddInt64(-1)))
+	err3 := bc.PublishTx(newTransferWithFee(bc, aliceKey, cat, 10, coins(1)))
+
+	assert.True(t, minFee.Sign() > 0)
+	assert.Equal(t, txobj.ErrTxLowFee, err1)
+	assert.Equal(t, txobj.ErrTxLowFee, err2)
+	assert.NoError(t, err3)
+	assert.True(t, bc.Cfg.MinTxFee(1, len(tx.Data), 1).IsZero())
+}
+
+func TestStatistic_Decode_version0(t *testing.T) {
+	var zero bignum.Int
+	data := bin.Encode(0, uint64(10), int64(20), int64(0), int64(0), zero, zero, zero, int64(0), 0, 0, 0, 0)
+
+	var st Statistic
+	err := st.Decode(data)
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, st.Blocks)
+	assert.EqualValues(t, 20, st.Txs)
+	assert.True(t, st.Fees.IsZero())
+}
+
+func TestTransfer_Fee_simulate(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	miner := masterKey.PublicKey().Address()
+
+	upd, err := bc.SimulateTx(newTransferWithFee(bc, aliceKey, cat, 10, coins(2)), nil)
+
+	assert.NoError(t, err)
+	v := upd.Find(assets.MDC, miner)
+	assert.NotNil(t, v)
+	assert.Equal(t, coins(2), v.Balance)
+}
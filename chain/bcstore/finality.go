@@ -0,0 +1,101 @@
+package bcstore
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/finality"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/mediacoin-pro/core/crypto"
+)
+
+var (
+	ErrFinalizedBlock = errors.New("block has been finalized")
+	ErrCertNotFound   = errors.New("certificate not found")
+)
+
+var _ finality.Chain = new(ChainStorage)
+
+// ValidatorsAt returns validators set of the state before the block (the validators that finalize the block)
+func (s *ChainStorage) ValidatorsAt(num uint64) ([]*crypto.PublicKey, error) {
+	if num == 0 {
+		return nil, ErrBlockNotFound
+	}
+	if num-1 == s.LastBlockHeader().Num {
+		return chain.Validators(s), nil
+	}
+	h, err := s.BlockHeader(num - 1)
+	if err != nil {
+		return nil, err
+	}
+	return chain.Validators(&historicalContext{s, h}), nil
+}
+
+// PutCertificate verifies and stores the certificate of finality of the block
+func (s *ChainStorage) PutCertificate(cert *finality.Certificate) error {
+	s.mxW.Lock()
+	defer s.mxW.Unlock()
+
+	if cert.Num <= s.FinalizedNum() {
+		return nil
+	}
+	h, err := s.BlockHeader(cert.Num)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Hash(), cert.BlockHash) {
+		return finality.ErrInvalidCert
+	}
+	validators, err := s.ValidatorsAt(cert.Num)
+	if err != nil {
+		return err
+	}
+	if err = cert.Verify(s.Cfg.ChainID, validators); err != nil {
+		return err
+	}
+	if err = s.db.PutVar(goldb.Key(dbTabCerts, cert.Num), cert); err != nil {
+		return err
+	}
+
+	s.mxR.Lock()
+	s.finalized = cert.Num
+	s.mxR.Unlock()
+	return nil
+}
+
+// Certificate returns certificate of finality of the block
+func (s *ChainStorage) Certificate(num uint64) (*finality.Certificate, error) {
+	cert := new(finality.Certificate)
+	if ok, err := s.db.GetVar(goldb.Key(dbTabCerts, num), cert); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrCertNotFound
+	}
+	return cert, nil
+}
+
+// FinalizedNum returns num of last finalized block (0 if there are no finalized blocks)
+func (s *ChainStorage) FinalizedNum() uint64 {
+	s.mxR.RLock()
+	defer s.mxR.RUnlock()
+	return s.finalized
+}
+
+// FinalizedBlock returns last finalized block (genesis block if there are no finalized blocks)
+func (s *ChainStorage) FinalizedBlock() (*chain.Block, error) {
+	return s.GetBlock(s.FinalizedNum())
+}
+
+// IsFinal returns true if the block has been finalized. Finalized blocks can not be reverted.
+func (s *ChainStorage) IsFinal(num uint64) bool {
+	return num <= s.FinalizedNum()
+}
+
+func (s *ChainStorage) queryFinalizedNum() (num uint64, err error) {
+	var cert finality.Certificate
+	if err = s.db.QueryValue(goldb.NewQuery(dbTabCerts).Last(), &cert); err != nil {
+		return
+	}
+	return cert.Num, nil
+}
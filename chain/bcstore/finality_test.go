@@ -0,0 +1,182 @@
+package bcstore
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/finality"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var validator4 = crypto.NewPrivateKey()
+
+// testNetwork is in-process network of validators; votes are delivered in order of broadcasting
+type testNetwork struct {
+	nodes []*finality.Validator
+	queue []*finality.Vote
+}
+
+func (n *testNetwork) Broadcast(v *finality.Vote) {
+	n.queue = append(n.queue, v)
+}
+
+func (n *testNetwork) deliver() {
+	for len(n.queue) > 0 {
+		v := n.queue[0]
+		n.queue = n.queue[1:]
+		for _, node := range n.nodes {
+			node.OnVote(v)
+		}
+	}
+}
+
+// newTestFinalityChain returns PoA-chain with validators set {validator1, validator2, validator3, validator4}
+func newTestFinalityChain(t *testing.T) *ChainStorage {
+	bc := newTestPoAChain(t)
+	add := []*crypto.PublicKey{validator3.PublicKey(), validator4.PublicKey()}
+	_, err := bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewValidatorsUpd(bc, nil, aliceKey, add, nil, []*txobj.ValidatorSig{
+			txobj.SignValidatorsUpd(bc, add, nil, validator1),
+			txobj.SignValidatorsUpd(bc, add, nil, validator2),
+		}),
+	}, validator2)
+	assert.NoError(t, err)
+	return bc
+}
+
+// replicate copies all blocks of the chain to new storage
+func replicate(t *testing.T, bc *ChainStorage) *ChainStorage {
+	c := NewChainStorage(fmt.Sprintf("%s/test-bcstore-%x.db", os.TempDir(), rand.Int()), bc.Cfg)
+	blocks, err := bc.GetBlocks(0, 0, false)
+	assert.NoError(t, err)
+	for _, b := range blocks {
+		assert.NoError(t, c.PutEncodedBlocks(b.Encode()))
+	}
+	return c
+}
+
+func TestChainStorage_Finality(t *testing.T) {
+	bc := newTestFinalityChain(t)
+	defer bc.Drop()
+
+	// network of 3 honest validators
+	net := &testNetwork{}
+	var chains []*ChainStorage
+	for _, key := range []*crypto.PrivateKey{validator1, validator2, validator3} {
+		c := replicate(t, bc)
+		defer c.Drop()
+		chains = append(chains, c)
+		net.nodes = append(net.nodes, finality.NewValidator(c, key, net))
+	}
+	// faulty validator4 votes for another block and sends invalid votes
+	block, err := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, bob, 1)}, validator1)
+	assert.NoError(t, err)
+	fakeHeader := *block.BlockHeader
+	fakeHeader.Nonce++
+	net.Broadcast(finality.NewVote(finality.Prevote, &fakeHeader, validator4))
+	net.Broadcast(finality.NewVote(finality.Precommit, &fakeHeader, validator4))
+	invalidVote := finality.NewVote(finality.Precommit, block.BlockHeader, validator4)
+	invalidVote.Num++
+	net.Broadcast(invalidVote)
+
+	// the new block is delivered to honest validators
+	for i, c := range chains {
+		assert.NoError(t, c.PutEncodedBlocks(block.Encode()))
+		assert.NoError(t, net.nodes[i].OnNewBlock())
+	}
+	net.deliver()
+
+	for _, c := range chains {
+		assert.EqualValues(t, 4, c.FinalizedNum())
+		assert.True(t, c.IsFinal(4))
+		assert.True(t, c.IsFinal(3))
+		assert.False(t, c.IsFinal(5))
+
+		fb, err := c.FinalizedBlock()
+		assert.NoError(t, err)
+		assert.Equal(t, block.Hash(), fb.Hash())
+
+		cert, err := c.Certificate(4)
+		assert.NoError(t, err)
+		assert.Equal(t, block.Hash(), []byte(cert.BlockHash))
+		assert.NoError(t, cert.Verify(c.Cfg.ChainID, chain.Validators(c)))
+
+		// finalized block can not be reverted
+		assert.ErrorIs(t, c.RollbackTo(3), ErrFinalizedBlock)
+	}
+	// certificate is restored on restart
+	c := chains[0]
+	assert.NoError(t, c.Close())
+	c = NewChainStorage(c.Dir, c.Cfg)
+	defer c.Close()
+	assert.EqualValues(t, 4, c.FinalizedNum())
+}
+
+func TestChainStorage_Finality_noQuorum(t *testing.T) {
+	bc := newTestFinalityChain(t)
+	defer bc.Drop()
+
+	// 2 of 4 validators are offline
+	net := &testNetwork{}
+	for _, key := range []*crypto.PrivateKey{validator1, validator2} {
+		net.nodes = append(net.nodes, finality.NewValidator(bc, key, net))
+	}
+	_, err := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, bob, 1)}, validator1)
+	assert.NoError(t, err)
+	for _, node := range net.nodes {
+		assert.NoError(t, node.OnNewBlock())
+	}
+	net.deliver()
+
+	assert.EqualValues(t, 3, bc.FinalizedNum()) // blocks of previous validators set {validator1, validator2}
+	assert.False(t, bc.IsFinal(4))
+	assert.NoError(t, bc.RollbackTo(3))
+}
+
+func TestChainStorage_PutCertificate(t *testing.T) {
+	bc := newTestFinalityChain(t)
+	defer bc.Drop()
+	block, err := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, bob, 1)}, validator1)
+	assert.NoError(t, err)
+
+	precommits := func(h *chain.BlockHeader, keys ...*crypto.PrivateKey) (vv []*finality.Vote) {
+		for _, key := range keys {
+			vv = append(vv, finality.NewVote(finality.Precommit, h, key))
+		}
+		return
+	}
+	fakeHeader := *block.BlockHeader
+	fakeHeader.Nonce++
+
+	err0 := bc.PutCertificate(&finality.Certificate{
+		Num:        block.Num,
+		BlockHash:  block.Hash(),
+		Precommits: precommits(block.BlockHeader, validator1, validator2, validator2),
+	})
+	err1 := bc.PutCertificate(&finality.Certificate{
+		Num:        block.Num,
+		BlockHash:  block.Hash(),
+		Precommits: precommits(block.BlockHeader, validator1, validator2, masterKey),
+	})
+	err2 := bc.PutCertificate(&finality.Certificate{
+		Num:        block.Num,
+		BlockHash:  fakeHeader.Hash(),
+		Precommits: precommits(&fakeHeader, validator1, validator2, validator3),
+	})
+	err3 := bc.PutCertificate(&finality.Certificate{
+		Num:        block.Num,
+		BlockHash:  block.Hash(),
+		Precommits: precommits(block.BlockHeader, validator1, validator2, validator3),
+	})
+
+	assert.ErrorIs(t, err0, finality.ErrNoQuorum)
+	assert.ErrorIs(t, err1, finality.ErrNotValidator)
+	assert.ErrorIs(t, err2, finality.ErrInvalidCert)
+	assert.NoError(t, err3)
+	assert.EqualValues(t, 4, bc.FinalizedNum())
+}
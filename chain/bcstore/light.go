@@ -0,0 +1,225 @@
+package bcstore
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/crypto/merkle"
+)
+
+// Light-client protocol (see: lightclient.Client)
+const (
+	LightGetHeaders = 1 // request: Offset, Limit;   response: []*chain.BlockHeader
+	LightGetBalance = 2 // request: Asset, Address;  response: *BalanceProof
+	LightGetTx      = 3 // request: TxID;            response: *TxProof
+
+	maxLightHeaders = 1000
+)
+
+var (
+	ErrInvalidProof        = errors.New("bcstore: invalid proof")
+	errInvalidLightRequest = errors.New("bcstore: invalid light-client request")
+)
+
+// LightRequest is request of light client
+type LightRequest struct {
+	Method  int
+	Offset  uint64
+	Limit   int
+	Asset   bin.Bytes
+	Address bin.Bytes
+	TxID    uint64
+}
+
+func (r *LightRequest) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		r.Method,
+		r.Offset,
+		r.Limit,
+		r.Asset,
+		r.Address,
+		r.TxID,
+	)
+}
+
+func (r *LightRequest) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&r.Method,
+		&r.Offset,
+		&r.Limit,
+		&r.Asset,
+		&r.Address,
+		&r.TxID,
+	)
+}
+
+// BalanceProof is proof of state value by the state root of the last block
+type BalanceProof struct {
+	Header  *chain.BlockHeader // header of the last block
+	Asset   bin.Bytes
+	Address bin.Bytes
+	Value   bin.Bytes // bytes of balance (see: state.Value.Balance)
+	Proof   bin.Bytes // patricia-proof by Header.StateRoot
+}
+
+func (p *BalanceProof) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		p.Header,
+		p.Asset,
+		p.Address,
+		p.Value,
+		p.Proof,
+	)
+}
+
+func (p *BalanceProof) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&p.Header,
+		&p.Asset,
+		&p.Address,
+		&p.Value,
+		&p.Proof,
+	)
+}
+
+// Verify verifies the value by the state root of the header
+func (p *BalanceProof) Verify() error {
+	if p.Header == nil {
+		return ErrInvalidProof
+	}
+	key := append(append([]byte{}, p.Address...), p.Asset...) // see: state.Value.StateKey
+	if !merkle.Verify(merkle.Root(key, p.Value), p.Proof, p.Header.StateRoot) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// TxProof is proof of transaction by the txs root of its block,
+// and proof of the block by the chain root of the last block
+type TxProof struct {
+	Tx             *chain.Transaction
+	TxInclusion    *TxInclusionProof
+	BlockInclusion *BlockInclusionProof // proof of the block of the transaction by the last block
+}
+
+func (p *TxProof) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		p.Tx,
+		p.TxInclusion,
+		p.BlockInclusion,
+	)
+}
+
+func (p *TxProof) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&p.Tx,
+		&p.TxInclusion,
+		&p.BlockInclusion,
+	)
+}
+
+// Verify verifies the transaction by the txs root of the block and the block by the chain root of the last block
+func (p *TxProof) Verify() error {
+	if p.TxInclusion == nil || p.BlockInclusion == nil {
+		return ErrInvalidProof
+	}
+	if err := p.TxInclusion.VerifyTx(p.Tx); err != nil {
+		return err
+	}
+	if h := p.TxInclusion.Header; h.Num != p.BlockInclusion.Num || !bytes.Equal(h.Hash(), p.BlockInclusion.BlockHash) {
+		return ErrInvalidProof
+	}
+	return p.BlockInclusion.Verify()
+}
+
+// ServeLight handles encoded request of light client (see: LightRequest) and returns encoded response
+func (s *ChainStorage) ServeLight(data []byte) ([]byte, error) {
+	var req LightRequest
+	if err := req.Decode(data); err != nil {
+		return nil, err
+	}
+	switch req.Method {
+	case LightGetHeaders:
+		limit := req.Limit
+		if limit <= 0 || limit > maxLightHeaders {
+			limit = maxLightHeaders
+		}
+		var headers []*chain.BlockHeader
+		err := s.FetchBlockHeaders(req.Offset, int64(limit), false, func(h *chain.BlockHeader) error {
+			headers = append(headers, h)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return bin.Encode(headers), nil
+
+	case LightGetBalance:
+		p, err := s.BalanceProof(req.Asset, req.Address)
+		if err != nil {
+			return nil, err
+		}
+		return p.Encode(), nil
+
+	case LightGetTx:
+		p, err := s.TxProof(req.TxID)
+		if err != nil {
+			return nil, err
+		}
+		return p.Encode(), nil
+	}
+	return nil, errInvalidLightRequest
+}
+
+// BalanceProof returns proof of state value by the state root of the last block
+func (s *ChainStorage) BalanceProof(asset, addr []byte) (*BalanceProof, error) {
+	// lock writing to get state tree of the last block
+	s.mxW.Lock()
+	defer s.mxW.Unlock()
+
+	header := s.LastBlockHeader()
+	key := append(append([]byte{}, addr...), asset...)
+	value, proof, root, err := s.StateTree().GetProof(key)
+	if err != nil {
+		return nil, ErrAddrNotFound
+	}
+	if !bytes.Equal(root, header.StateRoot) {
+		return nil, errIncorrectStateRoot
+	}
+	return &BalanceProof{
+		Header:  header,
+		Asset:   asset,
+		Address: addr,
+		Value:   value,
+		Proof:   proof,
+	}, nil
+}
+
+// TxProof returns proof of the transaction by txs root of its block and chain root of the last block
+func (s *ChainStorage) TxProof(txID uint64) (*TxProof, error) {
+	txProof, err := s.TxInclusionProof(txID)
+	if err != nil {
+		return nil, err
+	}
+	blockProof, err := s.BlockInclusionProof(txProof.Header.Num, 0)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.TransactionByID(txID)
+	if err != nil {
+		return nil, err
+	}
+	return &TxProof{
+		Tx:             tx,
+		TxInclusion:    txProof,
+		BlockInclusion: blockProof,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package bcstore
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainStorage_BalanceProof(t *testing.T) {
+	bc := newTestArchiveChain(t, 5)
+	defer bc.Drop()
+
+	p, err := bc.BalanceProof(assets.MDC, cat)
+	assert.NoError(t, err)
+
+	var p1 BalanceProof
+	assert.NoError(t, p1.Decode(p.Encode()))
+	assert.NoError(t, p1.Verify())
+	assert.Equal(t, bc.LastBlock().Hash(), p1.Header.Hash())
+	assert.EqualValues(t, 4, bignum.NewFromBytes(p1.Value).Int64()/assets.Coin)
+
+	p1.Address = bob
+	assert.Equal(t, ErrInvalidProof, p1.Verify())
+
+	_, err = bc.BalanceProof(assets.MDC, masterKey.PublicKey().Address())
+	assert.Equal(t, ErrAddrNotFound, err)
+}
+
+func TestChainStorage_TxProof(t *testing.T) {
+	bc := newTestArchiveChain(t, 5)
+	defer bc.Drop()
+	_, err := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 1), newTransfer(bc, bobKey, cat, 1), newTransfer(bc, aliceKey, bob, 1)}, masterKey)
+	assert.NoError(t, err)
+	block, _ := bc.GetBlock(6)
+
+	for _, tx := range block.Txs {
+		p, err := bc.TxProof(tx.ID())
+		assert.NoError(t, err)
+
+		var p1 TxProof
+		assert.NoError(t, p1.Decode(p.Encode()))
+		assert.NoError(t, p1.Verify())
+		assert.Equal(t, tx.Encode(), p1.Tx.Encode())
+	}
+
+	p, _ := bc.TxProof(block.Txs[0].ID())
+	p.BlockInclusion.Num--
+	assert.Equal(t, ErrInvalidProof, p.Verify())
+}
+
+func TestChainStorage_ServeLight(t *testing.T) {
+	bc := newTestArchiveChain(t, 5)
+	defer bc.Drop()
+
+	data, err := bc.ServeLight((&LightRequest{Method: LightGetHeaders, Offset: 2}).Encode())
+	assert.NoError(t, err)
+	var headers []*chain.BlockHeader
+	assert.NoError(t, bin.Decode(data, &headers))
+	assert.Equal(t, 3, len(headers))
+	assert.EqualValues(t, 3, headers[0].Num)
+
+	_, err = bc.ServeLight((&LightRequest{Method: 100}).Encode())
+	assert.Error(t, err)
+}
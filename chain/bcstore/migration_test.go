@@ -0,0 +1,89 @@
+package bcstore
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewChainStorage_schemaVersion(t *testing.T) {
+	bc := newTestStorage()
+	defer bc.Drop()
+
+	ver, err := bc.SchemaVersion()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ver)
+}
+
+func TestMigrateDir(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+	dump := dumpIndexes(bc)
+	// old storage without index by type and schema version
+	bc.db.RemoveByQuery(goldb.NewQuery(dbIdxTypeAssetAddr))
+	bc.db.SetSchemaVersion(1)
+	bc.Close()
+
+	pending, err0 := MigrateDir(bc.Dir, bc.Cfg, &goldb.MigrateOptions{DryRun: true})
+	var progress []goldb.MigrationProgress
+	applied, err1 := MigrateDir(bc.Dir, bc.Cfg, &goldb.MigrateOptions{
+		Progress: func(p goldb.MigrationProgress) {
+			progress = append(progress, p)
+		},
+	})
+	bc.db.Open()
+
+	assert.NoError(t, err0)
+	assert.NoError(t, err1)
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, 2, pending[0].Version)
+	assert.Equal(t, 1, len(applied))
+	assert.Equal(t, 1, len(progress))
+	assert.EqualValues(t, 3, progress[0].Done)
+	assert.EqualValues(t, 3, progress[0].Total)
+	assert.Equal(t, dump, dumpIndexes(bc))
+	ver, _ := bc.SchemaVersion()
+	assert.Equal(t, 2, ver)
+}
+
+func TestMigrateDir_completedReindex(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+	// index has been rebuilt but schema version has not been updated
+	bc.db.RemoveByQuery(goldb.NewQuery(dbIdxTypeAssetAddr))
+	bc.db.SetSchemaVersion(1)
+	assert.NoError(t, bc.Reindex(IndexTypeAssetAddr))
+	dump := dumpIndexes(bc)
+	bc.Close()
+
+	var progress []goldb.MigrationProgress
+	applied, err := MigrateDir(bc.Dir, bc.Cfg, &goldb.MigrateOptions{
+		Progress: func(p goldb.MigrationProgress) {
+			progress = append(progress, p)
+		},
+	})
+	bc.db.Open()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(applied))
+	assert.Equal(t, 0, len(progress))
+	assert.Equal(t, dump, dumpIndexes(bc))
+	ver, _ := bc.SchemaVersion()
+	assert.Equal(t, 2, ver)
+}
+
+func TestNewChainStorage_newerSchema(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.db.SetSchemaVersion(100)
+	bc.Close()
+
+	_, err := VerifyDir(bc.Dir, bc.Cfg)
+
+	assert.Equal(t, goldb.ErrNewerSchema, err)
+	assert.Panics(t, func() {
+		NewChainStorage(bc.Dir, bc.Cfg)
+	})
+}
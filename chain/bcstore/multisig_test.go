@@ -0,0 +1,96 @@
+package bcstore
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultisig(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	key1, key2, key3 := crypto.NewPrivateKey(), crypto.NewPrivateKey(), crypto.NewPrivateKey()
+
+	// alice account is protected by 2 of 3 keys
+	ok := putTx(t, bc, txobj.NewMultisig(bc, nil, aliceKey, 2, []*crypto.PublicKey{key1.PublicKey(), key2.PublicKey(), key3.PublicKey()}, 0))
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, bc.State().MultisigPolicy(alice).Threshold)
+
+	// key of the account is not enough
+	assert.Equal(t, chain.ErrInvalidTxSig, verifyTx(bc, newTransfer(bc, aliceKey, cat, 1)))
+
+	// unsigned tx is signed offline by keys of the account
+	tx := chain.NewUnsignedTx(bc, aliceKey.PublicKey(), 0, &txobj.Transfer{Outs: []*txobj.TransferOutput{{
+		Asset:     assets.MDC,
+		Amount:    coins(10),
+		To:        cat,
+		ToChainID: bc.Cfg.ChainID,
+	}}})
+	data := tx.Encode()
+	signBy := func(prv *crypto.PrivateKey) *crypto.KeySig {
+		var tx1 chain.Transaction
+		assert.NoError(t, tx1.Decode(data))
+		return tx1.SignPartial(prv)
+	}
+	sig1, sig3 := signBy(key1), signBy(key3)
+
+	tx.CombineSigs(sig1)
+	assert.Equal(t, chain.ErrInvalidTxSig, verifyTx(bc, tx))
+	tx.CombineSigs(sig1, sig1)
+	assert.Equal(t, chain.ErrInvalidTxSig, verifyTx(bc, tx))
+	tx.CombineSigs(sig1, signBy(bobKey))
+	assert.Equal(t, chain.ErrInvalidTxSig, verifyTx(bc, tx))
+	tx.CombineSigs(sig3, sig1)
+	assert.NoError(t, verifyTx(bc, tx))
+
+	ok = putTx(t, bc, tx)
+	assert.True(t, ok)
+	assert.EqualValues(t, 10, balance(bc, cat))
+
+	// policy is removed by signatures of the policy
+	rm := chain.NewUnsignedTx(bc, aliceKey.PublicKey(), 0, &txobj.Multisig{})
+	rm.CombineSigs(rm.SignPartial(key2), rm.SignPartial(key3))
+	ok = putTx(t, bc, rm)
+	assert.True(t, ok)
+	assert.Nil(t, bc.State().MultisigPolicy(alice))
+	assert.NoError(t, verifyTx(bc, newTransfer(bc, aliceKey, cat, 1)))
+}
+
+func TestMultisig_invalidPolicy(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	key1, key2 := crypto.NewPrivateKey().PublicKey(), crypto.NewPrivateKey().PublicKey()
+
+	for _, tx := range []*chain.Transaction{
+		txobj.NewMultisig(bc, nil, aliceKey, 3, []*crypto.PublicKey{key1, key2}, 0),
+		txobj.NewMultisig(bc, nil, aliceKey, 0, []*crypto.PublicKey{key1, key2}, 0),
+		txobj.NewMultisig(bc, nil, aliceKey, 2, []*crypto.PublicKey{key1, key1}, 0),
+	} {
+		assert.Equal(t, txobj.ErrTxIncorrectPolicy, verifyTx(bc, tx))
+	}
+}
+
+func TestTransfer_systemAssets(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	attacker := crypto.NewPrivateKey().PublicKey()
+	ok := putTx(t, bc, txobj.NewMultisig(bc, nil, aliceKey, 1, []*crypto.PublicKey{attacker}, 0))
+	assert.True(t, ok)
+
+	for _, asset := range [][]byte{assets.MULTI, assets.SEQ, assets.AUTH, assets.POA, assets.INFO, assets.SUPPLY} {
+		tx := txobj.NewSimpleTransfer(bc, nil, bobKey, asset, bignum.NewInt(1), 0, alice, 0, "", 0)
+		assert.Equal(t, txobj.ErrTxIncorrectAsset, verifyTx(bc, tx))
+	}
+
+	// policy of alice can not be moved to bob
+	value := bc.State().Get(assets.MULTI, alice)
+	tx := txobj.NewSimpleTransfer(bc, nil, aliceKey, assets.MULTI, value, 0, bob, 0, "", 0)
+	assert.Equal(t, txobj.ErrTxIncorrectAsset, tx.TxObject().Verify())
+	assert.Nil(t, bc.State().MultisigPolicy(bob))
+	assert.NoError(t, verifyTx(bc, newTransfer(bc, bobKey, cat, 1)))
+}
@@ -0,0 +1,113 @@
+package bcstore
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mediacoin-pro/core/chain"
+)
+
+// PutEncodedBlocks decodes blocks in parallel and puts them to storage
+func (s *ChainStorage) PutEncodedBlocks(data ...[]byte) error {
+	blocks := make([]*chain.Block, len(data))
+	err := parallel(s.Cfg.VerifyWorkers(), len(data), func(i int) error {
+		blocks[i] = new(chain.Block)
+		return blocks[i].Decode(data[i])
+	})
+	if err != nil {
+		return err
+	}
+	return s.PutBlock(blocks...)
+}
+
+// verifyBlocks verifies block headers and stateless transaction data (signatures) in parallel.
+// State of transactions is verified sequentially by putBlocks.
+// Transactions of the first trusted blocks are not verified (see: trustedBlocks).
+func (s *ChainStorage) verifyBlocks(blocks []*chain.Block, trusted int) error {
+	workers := s.Cfg.VerifyWorkers()
+
+	var txs []*chain.Transaction
+	for i, block := range blocks {
+		for txIdx, tx := range block.Txs {
+			tx.SetBlockInfo(s, block.Num, txIdx, block.Timestamp)
+		}
+		if i >= trusted {
+			txs = append(txs, block.Txs...)
+		}
+	}
+
+	// verify block headers and merkle roots of transactions
+	lastBlockHeader := s.lastBlock.BlockHeader
+	err := parallel(workers, len(blocks), func(i int) error {
+		pre := lastBlockHeader
+		if i > 0 {
+			pre = blocks[i-1].BlockHeader
+		}
+		return blocks[i].Verify(pre, s.Cfg)
+	})
+	if err != nil {
+		return err
+	}
+
+	// verify transactions data and signatures
+	if s.Cfg.VerifyTxsLevel >= chain.VerifyTxLevel1 {
+		return parallel(workers, len(txs), func(i int) error {
+			return txs[i].PreVerify()
+		})
+	}
+	return nil
+}
+
+// parallel calls fn(i) for i in [0, n) by the pool of workers.
+// It returns the error of the lowest index i.
+func parallel(workers, n int, fn func(i int) error) error {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var (
+		wg     sync.WaitGroup
+		next   int64 = -1
+		failed int32
+		errs   = make([]error, n)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&failed) == 0 {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				if errs[i] = safeCall(fn, i); errs[i] != nil {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func safeCall(fn func(i int) error, i int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("bcstore: panic: %v", r)
+		}
+	}()
+	return fn(i)
+}
@@ -0,0 +1,72 @@
+package bcstore
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallel(t *testing.T) {
+	var sum int64
+
+	err := parallel(4, 1000, func(i int) error {
+		atomic.AddInt64(&sum, int64(i))
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 999*1000/2, sum)
+}
+
+func TestParallel_error(t *testing.T) {
+	err := parallel(4, 1000, func(i int) error {
+		if i >= 500 {
+			return errors.New("err")
+		}
+		if i == 10 {
+			panic("panic-10")
+		}
+		return nil
+	})
+
+	assert.Equal(t, "bcstore: panic: panic-10", err.Error())
+}
+
+func TestChainStorage_PutEncodedBlocks(t *testing.T) {
+	src := newTestChain(t)
+	defer src.Drop()
+	for i := 0; i < 5; i++ {
+		src.PutNewBlock([]*chain.Transaction{
+			newTransfer(src, aliceKey, cat, 1),
+			newTransfer(src, bobKey, cat, 2),
+		}, masterKey)
+	}
+	blocks, _ := src.GetBlocks(0, 100, false)
+	var data [][]byte
+	for _, block := range blocks {
+		data = append(data, block.Encode())
+	}
+
+	dst := newTestStorage()
+	defer dst.Drop()
+	err := dst.PutEncodedBlocks(data...)
+
+	assert.NoError(t, err)
+	assert.Equal(t, src.LastBlockHeader().Hash(), dst.LastBlockHeader().Hash())
+	assert.EqualValues(t, 15, balance(dst, cat))
+}
+
+func TestChainStorage_PutBlock_invalidTxSig(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	tx := newTransfer(bc, aliceKey, cat, 1)
+	tx.Sig = newTransfer(bc, bobKey, cat, 1).Sig
+
+	_, err := bc.PutNewBlock([]*chain.Transaction{tx}, masterKey)
+
+	assert.ErrorContains(t, err, chain.ErrInvalidTxSig.Error())
+	assert.EqualValues(t, 1, bc.LastBlock().Num)
+}
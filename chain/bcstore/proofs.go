@@ -0,0 +1,186 @@
+package bcstore
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/crypto/merkle"
+	"github.com/mediacoin-pro/core/crypto/patricia"
+)
+
+// TxInclusionProof is merkle-proof of transaction by the txs root of its block
+type TxInclusionProof struct {
+	Header *chain.BlockHeader // header of the block of the transaction
+	TxID   uint64             //
+	TxIdx  int                // index of transaction in the block
+	TxHash bin.Bytes          // hash of transaction with state (see: Transaction.TxStHash)
+	Proof  bin.Bytes          // merkle-path from TxHash to Header.TxRoot
+}
+
+func (p *TxInclusionProof) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		p.Header,
+		p.TxID,
+		p.TxIdx,
+		p.TxHash,
+		p.Proof,
+	)
+}
+
+func (p *TxInclusionProof) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&p.Header,
+		&p.TxID,
+		&p.TxIdx,
+		&p.TxHash,
+		&p.Proof,
+	)
+}
+
+func (p *TxInclusionProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Header *chain.BlockHeader `json:"header"`
+		TxID   uint64             `json:"tx_id"`
+		TxIdx  int                `json:"tx_idx"`
+		TxHash bin.Bytes          `json:"tx_hash"`
+		Proof  bin.Bytes          `json:"proof"`
+	}{p.Header, p.TxID, p.TxIdx, p.TxHash, p.Proof})
+}
+
+// Verify verifies the merkle-path of transaction by the txs root of the header
+func (p *TxInclusionProof) Verify() error {
+	if p.Header == nil || !merkle.Verify(p.TxHash, p.Proof, p.Header.TxRoot) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// VerifyTx verifies that the proof is proof of the transaction
+func (p *TxInclusionProof) VerifyTx(tx *chain.Transaction) error {
+	if tx == nil || tx.ID() != p.TxID || !bytes.Equal(tx.TxStHash(), p.TxHash) {
+		return ErrInvalidProof
+	}
+	return p.Verify()
+}
+
+// BlockInclusionProof is patricia-proof of block hash by the chain root of the later block
+type BlockInclusionProof struct {
+	Num       uint64             // num of the block
+	BlockHash bin.Bytes          // hash of the block
+	Head      *chain.BlockHeader // header of the later block
+	Proof     bin.Bytes          // patricia-proof of BlockHash by Head.ChainRoot
+}
+
+func (p *BlockInclusionProof) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		p.Num,
+		p.BlockHash,
+		p.Head,
+		p.Proof,
+	)
+}
+
+func (p *BlockInclusionProof) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&p.Num,
+		&p.BlockHash,
+		&p.Head,
+		&p.Proof,
+	)
+}
+
+func (p *BlockInclusionProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Num       uint64             `json:"height"`
+		BlockHash bin.Bytes          `json:"hash"`
+		Head      *chain.BlockHeader `json:"head"`
+		Proof     bin.Bytes          `json:"proof"`
+	}{p.Num, p.BlockHash, p.Head, p.Proof})
+}
+
+// Verify verifies the block hash by the chain root of the head
+func (p *BlockInclusionProof) Verify() error {
+	if p.Head == nil || p.Num == 0 || p.Num > p.Head.Num {
+		return ErrInvalidProof
+	}
+	key := bin.Encode(p.Num) // see: ChainTree
+	if !merkle.Verify(merkle.Root(key, p.BlockHash), p.Proof, p.Head.ChainRoot) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// TxInclusionProof returns merkle-proof of the transaction by the txs root of its block
+func (s *ChainStorage) TxInclusionProof(txID uint64) (*TxInclusionProof, error) {
+	tx, err := s.TransactionByID(txID)
+	if err != nil {
+		return nil, err
+	} else if tx == nil {
+		return nil, errTxNotFound
+	}
+	block, err := s.GetBlock(tx.BlockNum())
+	if err != nil {
+		return nil, err
+	}
+	var hh [][]byte
+	for _, t := range block.Txs {
+		hh = append(hh, t.TxStHash())
+	}
+	proof, _ := merkle.Proof(hh, tx.BlockIdx())
+	return &TxInclusionProof{
+		Header: block.BlockHeader,
+		TxID:   txID,
+		TxIdx:  tx.BlockIdx(),
+		TxHash: tx.TxStHash(),
+		Proof:  proof,
+	}, nil
+}
+
+// BlockInclusionProof returns proof of the block hash by the chain root of the block atHead (0 - last block).
+// Chain tree of previous head is rebuilt by block headers.
+func (s *ChainStorage) BlockInclusionProof(num, atHead uint64) (*BlockInclusionProof, error) {
+	// lock writing to get chain tree of the last block
+	s.mxW.Lock()
+	defer s.mxW.Unlock()
+
+	last := s.LastBlockHeader()
+	if atHead == 0 {
+		atHead = last.Num
+	}
+	if num == 0 || num > atHead || atHead > last.Num {
+		return nil, ErrBlockNotFound
+	}
+	head, err := s.BlockHeader(atHead)
+	if err != nil {
+		return nil, err
+	}
+	tree := s.ChainTree()
+	if atHead < last.Num {
+		tree = patricia.NewTree(nil)
+		err = s.FetchBlockHeaders(0, int64(atHead), false, func(h *chain.BlockHeader) error {
+			return tree.PutVar(h.Num, h.Hash())
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	blockHash, proof, root, err := tree.GetProof(bin.Encode(num))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(root, head.ChainRoot) {
+		return nil, errIncorrectChainRoot
+	}
+	return &BlockInclusionProof{
+		Num:       num,
+		BlockHash: blockHash,
+		Head:      head,
+		Proof:     proof,
+	}, nil
+}
@@ -0,0 +1,76 @@
+package bcstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainStorage_TxInclusionProof(t *testing.T) {
+	bc := newTestArchiveChain(t, 5)
+	defer bc.Drop()
+	_, err := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 1), newTransfer(bc, bobKey, cat, 1), newTransfer(bc, aliceKey, bob, 1)}, masterKey)
+	assert.NoError(t, err)
+	block, _ := bc.GetBlock(6)
+
+	for i, tx := range block.Txs {
+		p, err := bc.TxInclusionProof(tx.ID())
+		assert.NoError(t, err)
+
+		var p1 TxInclusionProof
+		assert.NoError(t, p1.Decode(p.Encode()))
+		assert.NoError(t, p1.VerifyTx(tx))
+		assert.Equal(t, i, p1.TxIdx)
+		assert.Equal(t, block.Hash(), p1.Header.Hash())
+		assert.Equal(t, ErrInvalidProof, p1.VerifyTx(block.Txs[(i+1)%3]))
+	}
+
+	_, err = bc.TxInclusionProof(123)
+	assert.Error(t, err)
+}
+
+func TestChainStorage_BlockInclusionProof(t *testing.T) {
+	bc := newTestArchiveChain(t, 10)
+	defer bc.Drop()
+
+	for _, atHead := range []uint64{0, 10, 7, 3} {
+		p, err := bc.BlockInclusionProof(3, atHead)
+		assert.NoError(t, err)
+
+		var p1 BlockInclusionProof
+		assert.NoError(t, p1.Decode(p.Encode()))
+		assert.NoError(t, p1.Verify())
+		h, _ := bc.BlockHeader(3)
+		assert.Equal(t, h.Hash(), []byte(p1.BlockHash))
+		if atHead != 0 {
+			assert.EqualValues(t, atHead, p1.Head.Num)
+		}
+	}
+
+	p, _ := bc.BlockInclusionProof(3, 0)
+	p.Num = 4
+	assert.Equal(t, ErrInvalidProof, p.Verify())
+
+	_, err1 := bc.BlockInclusionProof(8, 7)
+	_, err2 := bc.BlockInclusionProof(3, 11)
+	assert.Equal(t, ErrBlockNotFound, err1)
+	assert.Equal(t, ErrBlockNotFound, err2)
+}
+
+func TestBlockInclusionProof_MarshalJSON(t *testing.T) {
+	bc := newTestArchiveChain(t, 3)
+	defer bc.Drop()
+	p, _ := bc.BlockInclusionProof(2, 0)
+	block, _ := bc.GetBlock(2)
+	txp, _ := bc.TxInclusionProof(block.Txs[0].ID())
+
+	data1, err1 := json.Marshal(p)
+	data2, err2 := json.Marshal(txp)
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Contains(t, string(data1), `"height":2`)
+	assert.Contains(t, string(data2), `"tx_idx":0`)
+}
@@ -0,0 +1,330 @@
+package bcstore
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/mediacoin-pro/core/common/xlog"
+	"github.com/mediacoin-pro/core/model"
+)
+
+// IndexID is identifier of secondary index of chain storage.
+// Secondary indexes are derived from blocks data (tables dbTabHeaders, dbTabTxs) and can be rebuilt by Reindex.
+type IndexID int
+
+const (
+	IndexTxID          IndexID = dbIdxTxID          // txID => txUID
+	IndexAssetAddr     IndexID = dbIdxAssetAddr     // balances by asset and address
+	IndexAssetAddrMemo IndexID = dbIdxAssetAddrMemo // balances by asset, address and memo
+	IndexUserID        IndexID = dbIdxUserID        // userID => txUID
+	IndexUserNick      IndexID = dbIdxUserNick      // nick => txUID
+	IndexTypeAssetAddr IndexID = dbIdxTypeAssetAddr // transactions by type, asset and address
+	IndexInvites       IndexID = dbIdxInvites       // invited users by referrer
+	IndexStat          IndexID = dbTabStat          // series of totals statistic
+)
+
+// AllIndexes is list of all secondary indexes
+var AllIndexes = []IndexID{
+	IndexTxID,
+	IndexAssetAddr,
+	IndexAssetAddrMemo,
+	IndexUserID,
+	IndexUserNick,
+	IndexTypeAssetAddr,
+	IndexInvites,
+	IndexStat,
+}
+
+var allIndexes = newIndexSet(AllIndexes)
+
+// consensusIndexes are used by verification of blocks (state and duplicates of transactions and users)
+var consensusIndexes = newIndexSet([]IndexID{IndexTxID, IndexAssetAddr, IndexUserID, IndexUserNick})
+
+var errUnknownIndex = errors.New("bcstore: unknown index")
+
+const reindexBatchSize = 1000 // blocks per db-transaction
+
+type ReindexProgress struct {
+	Indexes     []IndexID // rebuilding indexes
+	BlockNum    uint64    // last reindexed block
+	CountBlocks uint64    // count of blocks in chain
+	Txs         int64     // count of reindexed transactions
+}
+
+type indexSet map[IndexID]bool
+
+func newIndexSet(indexes []IndexID) indexSet {
+	set := indexSet{}
+	for _, id := range indexes {
+		set[id] = true
+	}
+	return set
+}
+
+// Reindex drops and rebuilds secondary indexes from blocks data (all indexes by default).
+// Reindexing is resumable; interrupted reindex is continued on next start of the storage.
+func (s *ChainStorage) Reindex(indexes ...IndexID) error {
+	return s.ReindexEx(func(p ReindexProgress) {
+		xlog.Printf("- reindexed  txs:%d  blocks:%d/%d", p.Txs, p.BlockNum, p.CountBlocks)
+	}, indexes...)
+}
+
+// ReindexEx drops and rebuilds secondary indexes; fnProgress is called after each batch of blocks.
+// Blocks are not imported while consensus indexes (see: consensusIndexes) are rebuilt.
+func (s *ChainStorage) ReindexEx(fnProgress func(ReindexProgress), indexes ...IndexID) (err error) {
+	if len(indexes) == 0 {
+		indexes = AllIndexes
+	}
+	locked := false
+	for _, id := range indexes {
+		if !allIndexes[id] {
+			return errUnknownIndex
+		}
+		locked = locked || consensusIndexes[id]
+	}
+
+	// lock storage for the whole reindex of consensus indexes or for each batch of other indexes
+	if locked {
+		s.mxW.Lock()
+		defer s.mxW.Unlock()
+	}
+	lock := func() {
+		if !locked {
+			s.mxW.Lock()
+		}
+	}
+	unlock := func() {
+		if !locked {
+			s.mxW.Unlock()
+		}
+	}
+
+	// mark indexes for reindex and drop index data
+	markers := map[IndexID]uint64{} // indexID => next block num
+	for _, id := range indexes {
+		var next uint64
+		if ok, err := s.db.GetVar(goldb.Key(dbTabReindex, int(id)), &next); err != nil {
+			return err
+		} else if !ok || next == 0 {
+			lock()
+			err = s.dropIndex(id)
+			unlock()
+			if err != nil {
+				return err
+			}
+			next = 1
+		}
+		markers[id] = next
+	}
+
+	p := ReindexProgress{Indexes: indexes}
+
+	for {
+		p.CountBlocks = s.CountBlocks()
+		var done bool
+		lock()
+		done, err = s.reindexBatch(markers, &p)
+		unlock()
+		if err != nil || done {
+			break
+		}
+		if fnProgress != nil {
+			fnProgress(p)
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	// finish reindex
+	err = s.db.Exec(func(tr *goldb.Transaction) {
+		for id := range markers {
+			tr.Delete(goldb.Key(dbTabReindex, int(id)))
+			tr.PutVar(goldb.Key(dbTabReindexed, int(id)), p.CountBlocks)
+		}
+	})
+	if err == nil && markers[IndexStat] != 0 {
+		// refresh totals info
+		lock()
+		defer unlock()
+		var st *Statistic
+		if st, err = s.TotalsAt(time.Time{}); err == nil {
+			s.mxR.Lock()
+			s.stat = st
+			s.mxR.Unlock()
+		}
+	}
+	return
+}
+
+// ReindexDir rebuilds secondary indexes of chain storage in the directory (storage must be closed)
+func ReindexDir(dir string, cfg *chain.Config, fnProgress func(ReindexProgress), indexes ...IndexID) error {
+	s := newChainStorage(dir, cfg)
+	defer s.Close()
+	if _, err := s.Migrate(nil); err != nil {
+		return err
+	}
+	return s.ReindexEx(fnProgress, indexes...)
+}
+
+// dropIndex removes all records of the index; marker of the index is set to 0 (dropping) and then to 1 (first block).
+// It is called under lock of mxW.
+func (s *ChainStorage) dropIndex(id IndexID) error {
+	if err := s.db.PutVar(goldb.Key(dbTabReindex, int(id)), uint64(0)); err != nil {
+		return err
+	}
+	for {
+		var n int
+		err := s.db.Exec(func(tr *goldb.Transaction) {
+			tr.Fetch(goldb.NewQuery(goldb.Entity(id)).Limit(10000), func(rec goldb.Record) error {
+				tr.Delete(rec.Key)
+				n++
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return s.db.PutVar(goldb.Key(dbTabReindex, int(id)), uint64(1))
+}
+
+// reindexBatch rebuilds indexes of the next batch of blocks; it is called under lock of mxW
+func (s *ChainStorage) reindexBatch(markers map[IndexID]uint64, p *ReindexProgress) (done bool, err error) {
+	var start uint64 = math.MaxUint64
+	for _, next := range markers {
+		if next < start {
+			start = next
+		}
+	}
+	lastNum := s.LastBlock().Num
+	if start > lastNum {
+		return true, nil
+	}
+	end := start + reindexBatchSize - 1
+	if end > lastNum {
+		end = lastNum
+	}
+
+	// totals statistic of previous block
+	var stat = &Statistic{}
+	if next := markers[IndexStat]; next > 1 && next <= end {
+		if stat, err = s.BlockStat(next - 1); err != nil {
+			return
+		} else if stat == nil {
+			return false, ErrBlockNotFound
+		}
+	}
+
+	err = s.db.Exec(func(tr *goldb.Transaction) {
+		for num := start; num <= end; num++ {
+			block, err := s.GetBlock(num)
+			if err != nil {
+				tr.Fail(err)
+			}
+			ix := indexSet{}
+			for id, next := range markers {
+				ix[id] = next <= num
+			}
+			for txIdx, tx := range block.Txs {
+				s.putTxIndexes(tr, tx, encodeTxUID(num, txIdx), ix)
+				if ix[IndexStat] {
+					stat.addTx(tx)
+				}
+			}
+			if ix[IndexStat] {
+				stat.addBlock(block)
+				tr.PutVar(goldb.Key(dbTabStat, block.Timestamp, block.Num), stat)
+			}
+			p.Txs += int64(len(block.Txs))
+		}
+		for id := range markers {
+			if markers[id] <= end+1 {
+				tr.PutVar(goldb.Key(dbTabReindex, int(id)), end+1)
+			}
+		}
+	})
+	if err != nil {
+		return
+	}
+	for id := range markers {
+		if markers[id] <= end+1 {
+			markers[id] = end + 1
+		}
+	}
+	p.BlockNum = end
+	return
+}
+
+// putTxIndexes puts records of secondary indexes for the transaction
+func (s *ChainStorage) putTxIndexes(tr *goldb.Transaction, tx *chain.Transaction, txUID uint64, ix indexSet) {
+
+	// put index transaction by txID
+	if ix[IndexTxID] {
+		tr.PutID(goldb.Key(dbIdxTxID, tx.ID()), txUID)
+	}
+
+	if tx.Type == model.TxUser {
+		usr := tx.TxObject().(*txobj.User)
+		if ix[IndexUserID] {
+			tr.PutID(goldb.Key(dbIdxUserID, usr.UserID()), txUID)
+		}
+		if ix[IndexUserNick] {
+			tr.PutID(goldb.Key(dbIdxUserNick, usr.Nick), txUID)
+		}
+		// referrals
+		if ix[IndexInvites] && usr.ReferrerID != 0 {
+			tr.PutID(goldb.Key(dbIdxInvites, usr.ReferrerID, txUID), txUID)
+		}
+	}
+
+	// state indexes
+	for stIdx, v := range tx.StateUpdates {
+		if v.ChainID == s.Cfg.ChainID {
+			if ix[IndexAssetAddr] {
+				tr.PutVar(goldb.Key(dbIdxAssetAddr, v.Asset, v.Address, txUID, stIdx), v.Balance)
+			}
+			if ix[IndexTypeAssetAddr] {
+				tr.PutVar(goldb.Key(dbIdxTypeAssetAddr, tx.Type, v.Asset, v.Address, txUID), 0)
+			}
+			if ix[IndexAssetAddrMemo] && v.Memo != 0 { // change state with memo
+				tr.PutVar(goldb.Key(dbIdxAssetAddrMemo, v.Asset, v.Address, v.Memo, txUID, stIdx), v.Balance)
+			}
+		}
+	}
+}
+
+// isReindexed returns true if the last reindex of the index has been completed
+func (s *ChainStorage) isReindexed(id IndexID) (bool, error) {
+	var n uint64
+	if ok, err := s.db.GetVar(goldb.Key(dbTabReindex, int(id)), &n); err != nil || ok {
+		return false, err
+	}
+	return s.db.GetVar(goldb.Key(dbTabReindexed, int(id)), &n)
+}
+
+// interruptedReindex returns indexes of interrupted reindex
+func (s *ChainStorage) interruptedReindex() (indexes []IndexID) {
+	s.db.Fetch(goldb.NewQuery(dbTabReindex), func(rec goldb.Record) error {
+		var id int
+		rec.MustDecodeKey(&id)
+		indexes = append(indexes, IndexID(id))
+		return nil
+	})
+	return
+}
+
+// resumeReindex completes interrupted reindex (before the storage is used)
+func (s *ChainStorage) resumeReindex() error {
+	if indexes := s.interruptedReindex(); len(indexes) > 0 {
+		return s.Reindex(indexes...)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package bcstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChainWithUsers(t *testing.T) *ChainStorage {
+	bc := newTestChain(t)
+	bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewUser(bc, aliceKey, "alice", 0),
+		newTransfer(bc, aliceKey, cat, 10),
+	}, masterKey)
+	bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewUser(bc, catKey, "cat", aliceKey.PublicKey().ID()),
+		newTransfer(bc, bobKey, cat, 20),
+	}, masterKey)
+	return bc
+}
+
+func TestChainStorage_Reindex(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+	stat := bc.Totals()
+	dump := dumpIndexes(bc)
+	bc.db.RemoveByQuery(goldb.NewQuery(dbIdxAssetAddr))
+	bc.db.RemoveByQuery(goldb.NewQuery(dbIdxUserNick))
+	bc.db.RemoveByQuery(goldb.NewQuery(dbTabStat))
+	bc.db.Put(goldb.Key(dbIdxTxID, 123), []byte{1}) // wrong index record
+
+	var progress []ReindexProgress
+	err := bc.ReindexEx(func(p ReindexProgress) {
+		progress = append(progress, p)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, dump, dumpIndexes(bc))
+	assert.Equal(t, 1, len(progress))
+	assert.EqualValues(t, 3, progress[0].BlockNum)
+	assert.EqualValues(t, 5, progress[0].Txs)
+	assert.Equal(t, AllIndexes, progress[0].Indexes)
+	st := bc.Totals()
+	assert.Equal(t, stat.Encode(), st.Encode())
+	assert.EqualValues(t, 30, balance(bc, cat))
+	u, _ := bc.UserByNick("cat")
+	assert.NotNil(t, u)
+	assert.Empty(t, bc.interruptedReindex())
+}
+
+func TestChainStorage_Reindex_putBlock(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+	block, err := chain.GenerateNewBlock(bc, []*chain.Transaction{newTransfer(bc, bobKey, cat, 5)}, masterKey)
+	assert.NoError(t, err)
+	lastNum := bc.LastBlock().Num
+
+	// block is put while the index of balances is rebuilt
+	var putErr error
+	done := make(chan struct{})
+	err = bc.ReindexEx(func(p ReindexProgress) {
+		go func() {
+			defer close(done)
+			putErr = bc.PutBlock(block)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, lastNum, bc.LastBlock().Num) // importing of blocks is locked
+	}, IndexAssetAddr)
+	<-done
+
+	assert.NoError(t, err)
+	assert.NoError(t, putErr)
+	assert.Equal(t, lastNum+1, bc.LastBlock().Num)
+	assert.EqualValues(t, 35, balance(bc, cat))
+	assert.Empty(t, bc.interruptedReindex())
+}
+
+func TestChainStorage_Reindex_resume(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+	dump := dumpIndexes(bc)
+
+	// interrupted reindex: blocks 1,2 have been reindexed
+	bc.db.RemoveByQuery(goldb.NewQuery(dbIdxAssetAddr).FilterFn(func(rec goldb.Record) bool {
+		var txUID uint64
+		rec.MustDecodeKey(new([]byte), new([]byte), &txUID)
+		return txUID>>32 == 3
+	}))
+	assert.NotEqual(t, dump, dumpIndexes(bc))
+	bc.db.PutVar(goldb.Key(dbTabReindex, int(IndexAssetAddr)), uint64(3))
+	assert.Equal(t, []IndexID{IndexAssetAddr}, bc.interruptedReindex())
+
+	err := bc.ReindexEx(nil, IndexAssetAddr)
+
+	assert.NoError(t, err)
+	assert.Equal(t, dump, dumpIndexes(bc))
+	assert.Empty(t, bc.interruptedReindex())
+}
+
+func TestNewChainStorage_resumeReindex(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	dump := dumpIndexes(bc)
+	bc.db.RemoveByQuery(goldb.NewQuery(dbIdxTxID))
+	bc.db.PutVar(goldb.Key(dbTabReindex, int(IndexTxID)), uint64(1))
+	bc.Close()
+
+	bc = NewChainStorage(bc.Dir, bc.Cfg)
+	defer bc.Drop()
+
+	// reindex is completed before the storage is used
+	assert.Empty(t, bc.interruptedReindex())
+	assert.Equal(t, dump, dumpIndexes(bc))
+}
+
+func TestReindexDir(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+	dump := dumpIndexes(bc)
+	bc.db.RemoveByQuery(goldb.NewQuery(dbIdxInvites))
+	bc.Close()
+
+	err := ReindexDir(bc.Dir, bc.Cfg, nil, IndexInvites)
+	bc.db.Open()
+
+	assert.NoError(t, err)
+	assert.Equal(t, dump, dumpIndexes(bc))
+}
+
+func TestChainStorage_Reindex_unknownIndex(t *testing.T) {
+	bc := newTestStorage()
+	defer bc.Drop()
+
+	err := bc.Reindex(dbTabHeaders)
+
+	assert.Equal(t, errUnknownIndex, err)
+}
+
+func dumpIndexes(bc *ChainStorage) (records []string) {
+	for _, id := range AllIndexes {
+		bc.db.Fetch(goldb.NewQuery(goldb.Entity(id)), func(rec goldb.Record) error {
+			records = append(records, rec.String())
+			return nil
+		})
+	}
+	return
+}
@@ -0,0 +1,210 @@
+package bcstore
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/mediacoin-pro/core/common/xlog"
+	"github.com/mediacoin-pro/core/crypto/patricia"
+	"github.com/mediacoin-pro/core/model"
+)
+
+var (
+	ErrInvalidReorgBranch = errors.New("invalid reorganization branch")
+)
+
+// RollbackTo reverts all blocks after blockNum.
+// Transactions of the reverted blocks are returned to mempool.
+func (s *ChainStorage) RollbackTo(blockNum uint64) error {
+	s.mxW.Lock()
+	defer s.mxW.Unlock()
+
+	_, err := s.rollbackTo(blockNum)
+	return err
+}
+
+// Reorg replaces the suffix of the chain by the alternative branch.
+// The first block of the branch must refer to the block of current chain (common ancestor).
+// If the branch can not be applied, the previous chain is restored.
+func (s *ChainStorage) Reorg(blocks ...*chain.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	s.mxW.Lock()
+	defer s.mxW.Unlock()
+
+	first := blocks[0]
+	if first.Num == 0 || first.Num > s.LastBlock().Num+1 {
+		return ErrInvalidReorgBranch
+	}
+	ancestor, err := s.BlockHeader(first.Num - 1)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(first.PrevHash, ancestor.Hash()) {
+		return ErrInvalidReorgBranch
+	}
+
+	reverted, err := s.rollbackTo(ancestor.Num)
+	if err != nil {
+		return err
+	}
+	if err = s.putBlocks(blocks); err != nil {
+		// restore previous branch
+		if err2 := s.putBlocks(reverted); err2 != nil {
+			xlog.Error.Printf("bcstore.Reorg: can not restore previous branch: %v", err2)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ChainStorage) rollbackTo(blockNum uint64) (reverted []*chain.Block, err error) {
+	lastNum := s.LastBlock().Num
+	if blockNum >= lastNum {
+		return
+	}
+	if blockNum < s.FinalizedNum() {
+		return nil, ErrFinalizedBlock
+	}
+	target, err := s.BlockHeader(blockNum)
+	if err != nil {
+		return
+	}
+	for num := blockNum + 1; num <= lastNum; num++ {
+		block, err := s.GetBlock(num)
+		if err != nil {
+			return nil, err
+		}
+		reverted = append(reverted, block)
+	}
+
+	var stat *Statistic
+
+	err = s.db.Exec(func(tr *goldb.Transaction) {
+
+		stateTree := patricia.NewSubTree(tr, goldb.Key(dbTabStateTree))
+		chainTree := patricia.NewSubTree(tr, goldb.Key(dbTabChainTree))
+
+		// changed state values by state-key
+		changes := map[string]*state.Value{}
+
+		for i := len(reverted) - 1; i >= 0; i-- {
+			block := reverted[i]
+
+			// middleware for each reverted block
+			for _, fn := range s.rbMiddleware {
+				fn(tr, block)
+			}
+
+			for txIdx, tx := range block.Txs {
+				txUID := encodeTxUID(block.Num, txIdx)
+
+				switch tx.Type {
+				case model.TxUser:
+					usr := tx.TxObject().(*txobj.User)
+					tr.Delete(goldb.Key(dbIdxUserID, usr.UserID()))
+					tr.Delete(goldb.Key(dbIdxUserNick, usr.Nick))
+					if usr.ReferrerID != 0 {
+						tr.Delete(goldb.Key(dbIdxInvites, usr.ReferrerID, txUID))
+					}
+				}
+
+				// remove state indexes
+				for stIdx, v := range tx.StateUpdates {
+					if v.ChainID == s.Cfg.ChainID {
+						tr.Delete(goldb.Key(dbIdxAssetAddr, v.Asset, v.Address, txUID, stIdx))
+						tr.Delete(goldb.Key(dbIdxTypeAssetAddr, tx.Type, v.Asset, v.Address, txUID))
+						if v.Memo != 0 {
+							tr.Delete(goldb.Key(dbIdxAssetAddrMemo, v.Asset, v.Address, v.Memo, txUID, stIdx))
+						}
+						changes[string(v.StateKey())] = v
+					}
+				}
+
+				// remove transaction data
+				tr.Delete(goldb.Key(dbIdxTxID, tx.ID()))
+				tr.Delete(goldb.Key(dbTabTxs, block.Num, txIdx))
+			}
+
+			tr.Delete(goldb.Key(dbTabStat, block.Timestamp, block.Num))
+			tr.Delete(goldb.Key(dbTabHeaders, block.Num))
+			chainTree.Delete(bin.Encode(block.Num))
+		}
+
+		// restore previous state values
+		for _, v := range changes {
+			var prev bignum.Int
+			if ok, _ := tr.Exists(goldb.NewQuery(dbIdxAssetAddr, v.Asset, v.Address)); !ok {
+				// state value has been created by reverted blocks
+				stateTree.Delete(v.StateKey())
+				continue
+			}
+			tr.QueryValue(goldb.NewQuery(dbIdxAssetAddr, v.Asset, v.Address).Last(), &prev)
+			stateTree.Put(v.StateKey(), prev.Bytes())
+		}
+
+		// verify state root
+		if stateRoot, _ := stateTree.Root(); !bytes.Equal(target.StateRoot, stateRoot) {
+			tr.Fail(errIncorrectStateRoot)
+		}
+
+		// verify chain root
+		if chainRoot, _ := chainTree.Root(); !bytes.Equal(target.ChainRoot, chainRoot) {
+			tr.Fail(errIncorrectChainRoot)
+		}
+
+		// totals at target block
+		tr.QueryValue(goldb.NewQuery(dbTabStat).Last(), &stat)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//--- success rollback commit ------
+	if stat == nil {
+		stat = &Statistic{}
+	}
+	lastBlock, err := s.queryLastBlock()
+	if err != nil {
+		return
+	}
+
+	// refresh last block and totals info
+	s.mxR.Lock()
+	s.lastBlock = lastBlock
+	s.stat = stat
+	s.mxR.Unlock()
+
+	s.cacheHeaders.Clear()
+	s.cacheTxs.Clear()
+	s.cacheIdxTx.Clear()
+	s.cacheNicks.Clear()
+
+	s.revertSubscribers(reverted)
+
+	// return txs to Mempool
+	for _, block := range reverted {
+		for _, tx := range block.Txs {
+			s.Mempool.Put(s.unconfirmedTx(tx))
+		}
+	}
+	return
+}
+
+// unconfirmedTx returns copy of the transaction without chain data
+func (s *ChainStorage) unconfirmedTx(tx *chain.Transaction) *chain.Transaction {
+	c := new(chain.Transaction)
+	if err := c.Decode(tx.Encode()); err != nil {
+		panic(err)
+	}
+	c.StateUpdates = nil
+	c.SetBlockInfo(s, 0, 0, 0)
+	return c
+}
@@ -0,0 +1,106 @@
+package bcstore
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainStorage_RollbackTo(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	block1 := bc.LastBlockHeader()
+	stat1 := bc.Totals()
+	_, err := bc.PutNewBlock([]*chain.Transaction{
+		newTransfer(bc, aliceKey, cat, 10),
+		txobj.NewUser(bc, catKey, "cat", 0),
+	}, masterKey)
+	assert.NoError(t, err)
+	_, err = bc.PutNewBlock([]*chain.Transaction{
+		newTransfer(bc, catKey, bob, 5),
+	}, masterKey)
+	assert.NoError(t, err)
+
+	err = bc.RollbackTo(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, block1.Hash(), bc.LastBlockHeader().Hash())
+	stat := bc.Totals()
+	assert.Equal(t, stat1.Encode(), stat.Encode())
+	assert.EqualValues(t, 1000, balance(bc, alice))
+	assert.EqualValues(t, 1000, balance(bc, bob))
+	assert.EqualValues(t, 0, balance(bc, cat))
+	assert.Equal(t, 3, bc.Mempool.Size())
+	u, _ := bc.UserByNick("cat")
+	assert.Nil(t, u)
+	b2, err := bc.GetBlock(2)
+	assert.Nil(t, b2)
+	assert.Equal(t, ErrBlockNotFound, err)
+
+	// put reverted txs again
+	block, err := bc.PutNewBlock(bc.Mempool.PopAll(), masterKey)
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, block.Num)
+	assert.EqualValues(t, 990, balance(bc, alice))
+	u, _ = bc.UserByNick("cat")
+	assert.NotNil(t, u)
+}
+
+func TestChainStorage_Reorg(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	// main branch
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 10)}, masterKey)
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 20)}, masterKey)
+	mainBranch, _ := bc.GetBlocks(1, 2, false)
+	assert.Equal(t, 2, len(mainBranch))
+
+	// alternative branch
+	err := bc.RollbackTo(1)
+	assert.NoError(t, err)
+	bc.Mempool.PopAll()
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, bobKey, cat, 1)}, masterKey)
+	assert.EqualValues(t, 2, bc.LastBlock().Num)
+	assert.EqualValues(t, 1, balance(bc, cat))
+
+	// reorg to main branch
+	err = bc.Reorg(mainBranch...)
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, bc.LastBlock().Num)
+	assert.Equal(t, mainBranch[1].Hash(), bc.LastBlockHeader().Hash())
+	assert.EqualValues(t, 30, balance(bc, cat))
+	assert.EqualValues(t, 1000, balance(bc, bob))
+	assert.Equal(t, 1, bc.Mempool.Size()) // tx of alternative branch
+}
+
+func TestChainStorage_Reorg_invalidBranch(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 10)}, masterKey)
+	block2 := bc.LastBlock()
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 20)}, masterKey)
+
+	// block with corrupted state
+	h := *block2.BlockHeader
+	h.StateRoot = make([]byte, 32)
+	err := bc.Reorg(chain.NewBlock(&h, block2.Txs))
+
+	assert.Error(t, err)
+	assert.EqualValues(t, 3, bc.LastBlock().Num)
+	assert.EqualValues(t, 30, balance(bc, cat))
+
+	// branch without common ancestor
+	h = *block2.BlockHeader
+	h.PrevHash = make([]byte, 32)
+	err = bc.Reorg(chain.NewBlock(&h, block2.Txs))
+
+	assert.Equal(t, ErrInvalidReorgBranch, err)
+	assert.EqualValues(t, 3, bc.LastBlock().Num)
+}
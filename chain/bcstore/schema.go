@@ -0,0 +1,68 @@
+package bcstore
+
+import (
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/mediacoin-pro/core/common/xlog"
+)
+
+// migrations returns ordered steps of upgrading of db-schema.
+// Version of last step is the current schema version of chain storage.
+func (s *ChainStorage) migrations() []*goldb.Migration {
+	return []*goldb.Migration{
+		{
+			Version: 1,
+			Name:    "initial schema",
+		},
+		{
+			Version: 2,
+			Name:    "index of transactions by type, asset and address",
+			Run: func(progress func(done, total int64)) error {
+				// index could be rebuilt before the schema version was updated
+				if ok, err := s.isReindexed(IndexTypeAssetAddr); err != nil || ok {
+					return err
+				}
+				return s.ReindexEx(func(p ReindexProgress) {
+					progress(int64(p.BlockNum), int64(p.CountBlocks))
+				}, IndexTypeAssetAddr)
+			},
+		},
+	}
+}
+
+// SchemaVersion returns current version of db-schema of chain storage
+func (s *ChainStorage) SchemaVersion() (int, error) {
+	return s.db.SchemaVersion()
+}
+
+// Migrate upgrades db-schema of chain storage
+func (s *ChainStorage) Migrate(opts *goldb.MigrateOptions) ([]*goldb.Migration, error) {
+	migrations := s.migrations()
+	if ver, err := s.db.SchemaVersion(); err != nil {
+		return nil, err
+	} else if ver == 0 && s.CountBlocks() == 0 && !(opts != nil && opts.DryRun) { // new storage
+		return nil, s.db.SetSchemaVersion(migrations[len(migrations)-1].Version)
+	}
+	return s.db.Migrate(migrations, opts)
+}
+
+// MigrateDir upgrades db-schema of chain storage in the directory (storage must be closed)
+func MigrateDir(dir string, cfg *chain.Config, opts *goldb.MigrateOptions) ([]*goldb.Migration, error) {
+	s := newChainStorage(dir, cfg)
+	defer s.Close()
+	return s.Migrate(opts)
+}
+
+func (s *ChainStorage) migrate() {
+	migrations, err := s.Migrate(&goldb.MigrateOptions{
+		Progress: func(p goldb.MigrationProgress) {
+			xlog.Printf("- migration #%d %s: %d/%d", p.Migration.Version, p.Migration.Name, p.Done, p.Total)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, m := range migrations {
+		xlog.Printf("- db-schema has been migrated to version #%d (%s)", m.Version, m.Name)
+	}
+}
@@ -0,0 +1,101 @@
+package bcstore
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSeqTransfer(bc *ChainStorage, from *crypto.PrivateKey, to []byte, amount int64, seq uint64) *chain.Transaction {
+	return chain.NewSeqTx(bc, nil, from, seq, &txobj.Transfer{Outs: []*txobj.TransferOutput{{
+		Asset:     assets.MDC,
+		Amount:    coins(amount),
+		To:        to,
+		ToChainID: bc.Cfg.ChainID,
+	}}})
+}
+
+func verifyTx(bc *ChainStorage, tx *chain.Transaction) error {
+	tx.SetBlockInfo(bc, 0, 0, 0)
+	return tx.Verify()
+}
+
+func TestSeqTx(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	// is not active
+	assert.Equal(t, chain.ErrTxSeqNotActive, verifyTx(bc, newSeqTransfer(bc, aliceKey, cat, 1, 1)))
+
+	bc.Cfg.SeqHeight = 2
+	assert.Equal(t, chain.ErrTxSeqGap, verifyTx(bc, newSeqTransfer(bc, aliceKey, cat, 1, 2)))
+	assert.NoError(t, verifyTx(bc, newTransfer(bc, aliceKey, cat, 1)))
+
+	ok := putTx(t, bc, newSeqTransfer(bc, aliceKey, cat, 1, 1))
+
+	assert.True(t, ok)
+	assert.EqualValues(t, 1, bc.State().Seq(alice))
+	assert.EqualValues(t, 1, balance(bc, cat))
+	assert.Equal(t, chain.ErrTxSeqDuplicate, verifyTx(bc, newSeqTransfer(bc, aliceKey, cat, 2, 1)))
+	assert.Equal(t, chain.ErrTxSeqRequired, verifyTx(bc, newTransfer(bc, aliceKey, cat, 1)))
+	assert.NoError(t, verifyTx(bc, newSeqTransfer(bc, aliceKey, cat, 1, 2)))
+
+	// transaction with gap is not executed
+	ok = putTx(t, bc, newSeqTransfer(bc, aliceKey, cat, 1, 3))
+	assert.False(t, ok)
+	assert.EqualValues(t, 1, bc.State().Seq(alice))
+}
+
+func TestSeqTx_mempool(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.Cfg.SeqHeight = 2
+	tx1, tx2, tx3 := newSeqTransfer(bc, aliceKey, cat, 1, 1), newSeqTransfer(bc, aliceKey, cat, 2, 2), newSeqTransfer(bc, aliceKey, cat, 3, 3)
+
+	// future sequences are queued
+	assert.NoError(t, bc.PublishTx(tx3))
+	assert.NoError(t, bc.PublishTx(tx2))
+	assert.Nil(t, bc.Mempool.Pop())
+	assert.Equal(t, 2, bc.Mempool.Size())
+
+	assert.NoError(t, bc.PublishTx(tx1))
+	txs := bc.Mempool.PopBatch(0, 0)
+	assert.Equal(t, []*chain.Transaction{tx1, tx2, tx3}, txs)
+
+	block, err := bc.PutNewBlock(txs, masterKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(block.Txs))
+	assert.EqualValues(t, 3, bc.State().Seq(alice))
+	assert.EqualValues(t, 6, balance(bc, cat))
+
+	// duplicates are rejected
+	assert.Equal(t, chain.ErrTxSeqDuplicate, bc.PublishTx(newSeqTransfer(bc, aliceKey, cat, 5, 3)))
+}
+
+func TestSeqTx_transferOfSeq(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.Cfg.SeqHeight = 2
+	assert.True(t, putTx(t, bc, newSeqTransfer(bc, aliceKey, cat, 1, 1)))
+	assert.True(t, putTx(t, bc, newSeqTransfer(bc, aliceKey, cat, 1, 2)))
+	victimTx := newSeqTransfer(bc, bobKey, cat, 1, 1)
+
+	// sequence number can not be moved to victim (or decreased by sender)
+	tx := chain.NewSeqTx(bc, nil, aliceKey, 3, &txobj.Transfer{Outs: []*txobj.TransferOutput{{
+		Asset:     assets.SEQ,
+		Amount:    bc.State().Get(assets.SEQ, alice),
+		To:        bob,
+		ToChainID: bc.Cfg.ChainID,
+	}}})
+	assert.Equal(t, txobj.ErrTxIncorrectAsset, verifyTx(bc, tx))
+	_, err := bc.PutNewBlock([]*chain.Transaction{tx}, masterKey)
+	assert.Error(t, err)
+
+	assert.EqualValues(t, 2, bc.State().Seq(alice))
+	assert.EqualValues(t, 0, bc.State().Seq(bob))
+	assert.NoError(t, verifyTx(bc, victimTx))
+}
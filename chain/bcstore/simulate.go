@@ -0,0 +1,155 @@
+package bcstore
+
+import (
+	"fmt"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/mediacoin-pro/core/model"
+)
+
+type SimulateOptions struct {
+	BlockNum    uint64 // simulate on the state after the block (last block by default)
+	WithMempool bool   // simulate on top of pending mempool transactions of the same senders
+}
+
+// SimulateError is error of simulation of transaction
+type SimulateError struct {
+	TxIdx int   // index of failed transaction in the batch
+	Err   error //
+}
+
+func (e *SimulateError) Error() string {
+	return fmt.Sprintf("bcstore.Simulate-error: tx#%d: %v", e.TxIdx, e.Err)
+}
+
+func (e *SimulateError) Unwrap() error {
+	return e.Err
+}
+
+// historicalContext is blockchain context with the state after the block
+type historicalContext struct {
+	*ChainStorage
+	header *chain.BlockHeader
+}
+
+func (c *historicalContext) LastBlockHeader() *chain.BlockHeader {
+	return c.header
+}
+
+// TransactionByID returns the transaction confirmed not later than the block
+func (c *historicalContext) TransactionByID(txID uint64) (*chain.Transaction, error) {
+	tx, err := c.ChainStorage.TransactionByID(txID)
+	if tx != nil && tx.BlockNum() > c.header.Num {
+		return nil, err
+	}
+	return tx, err
+}
+
+func (c *historicalContext) State() *state.State {
+	maxTxUID := encodeTxUID(c.header.Num+1, 0)
+	return state.NewState(c.Cfg.ChainID, func(asset, addr []byte) (v bignum.Int) {
+		if err := c.db.QueryValue(goldb.NewQuery(dbIdxAssetAddr, asset, addr).Offset(maxTxUID).OrderDesc().Limit(1), &v); err != nil {
+			panic(err)
+		}
+		return
+	})
+}
+
+// SimulateTx verifies and executes the transaction without committing.
+// It returns state-updates of the transaction or the error of verification or execution (see state.ErrNegativeValue).
+func (s *ChainStorage) SimulateTx(tx *chain.Transaction, opts *SimulateOptions) (state.Values, error) {
+	res, err := s.SimulateTxs([]*chain.Transaction{tx}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// SimulateTxs verifies and executes the transactions one by one without committing.
+// It returns state-updates of the transactions; on failure it returns *SimulateError.
+func (s *ChainStorage) SimulateTxs(txs []*chain.Transaction, opts *SimulateOptions) (res []state.Values, err error) {
+	if opts == nil {
+		opts = &SimulateOptions{}
+	}
+	var bc chain.BCContext = s
+	header := s.LastBlockHeader()
+	if opts.BlockNum != 0 && opts.BlockNum != header.Num {
+		if header, err = s.BlockHeader(opts.BlockNum); err != nil {
+			return
+		} else if header == nil {
+			return nil, ErrBlockNotFound
+		}
+		bc = &historicalContext{s, header}
+	}
+	txCtx := chain.NewSubContext(bc)
+	blockNum, blockTs := header.Num+1, chain.Timestamp()
+	miner := chain.ScheduledMiner(bc, blockNum) // fees are credited to the scheduled miner of the next block
+
+	// apply pending transactions of senders
+	if opts.WithMempool {
+		batch := map[uint64]bool{}
+		for _, tx := range txs {
+			batch[tx.ID()] = true
+		}
+		senders := map[string]bool{}
+		for _, tx := range txs {
+			addr := string(tx.SenderAddress())
+			if senders[addr] {
+				continue
+			}
+			senders[addr] = true
+			pending, _ := s.Mempool.TxsByAddress(tx.SenderAddress())
+			for _, ptx := range pending {
+				if batch[ptx.ID()] {
+					continue
+				}
+				if upd, err := s.simulateTx(txCtx, s.unconfirmedTx(ptx), blockNum, 0, blockTs, miner); err == nil {
+					txCtx.State().Apply(upd)
+				}
+			}
+		}
+	}
+
+	for i, tx := range txs {
+		upd, err := s.simulateTx(txCtx, s.unconfirmedTx(tx), blockNum, i, blockTs, miner)
+		if err != nil {
+			return res, &SimulateError{i, err}
+		}
+		txCtx.State().Apply(upd)
+		res = append(res, upd)
+	}
+	return
+}
+
+func (s *ChainStorage) simulateTx(txCtx chain.BCContext, tx *chain.Transaction, blockNum uint64, txIdx int, blockTs int64, miner *crypto.PublicKey) (state.Values, error) {
+	// check transaction by txID (in the state of the context)
+	if t, err := txCtx.TransactionByID(tx.ID()); err != nil {
+		return nil, err
+	} else if t != nil {
+		return nil, errTxHasBeenRegistered
+	}
+	if tx.Type == model.TxUser {
+		usr, err := tx.Object()
+		if err != nil {
+			return nil, err
+		}
+		maxTxUID := encodeTxUID(txCtx.LastBlockHeader().Num+1, 0)
+		if id, _ := s.db.GetID(goldb.Key(dbIdxUserID, usr.(*txobj.User).UserID())); id != 0 && id < maxTxUID {
+			return nil, errUserHasBeenRegistered
+		}
+		if id, _ := s.db.GetID(goldb.Key(dbIdxUserNick, usr.(*txobj.User).Nick)); id != 0 && id < maxTxUID {
+			return nil, errUserHasBeenRegistered
+		}
+	}
+	tx.SetBlockInfo(txCtx, blockNum, txIdx, blockTs)
+	tx.SetBlockMiner(miner)
+	if err := tx.Verify(); err != nil {
+		return nil, err
+	}
+	return tx.Execute()
+}
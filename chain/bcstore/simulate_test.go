@@ -0,0 +1,108 @@
+package bcstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainStorage_SimulateTx(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	tx := newTransfer(bc, aliceKey, cat, 10)
+
+	upd, err := bc.SimulateTx(tx, nil)
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1000, balance(bc, alice)) // state is not changed
+	assert.Nil(t, tx.StateUpdates)
+	block, _ := bc.PutNewBlock([]*chain.Transaction{tx}, masterKey)
+	assert.True(t, block.Txs[0].StateUpdates.Equal(upd))
+}
+
+func TestChainStorage_SimulateTx_notEnoughFunds(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	_, err := bc.SimulateTx(newTransfer(bc, catKey, alice, 1), nil)
+
+	assert.True(t, errors.Is(err, state.ErrNegativeValue))
+}
+
+func TestChainStorage_SimulateTx_registeredTx(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	tx := newTransfer(bc, aliceKey, cat, 10)
+	bc.PutNewBlock([]*chain.Transaction{tx}, masterKey)
+
+	_, err := bc.SimulateTx(tx, nil)
+
+	assert.True(t, errors.Is(err, errTxHasBeenRegistered))
+}
+
+func TestChainStorage_SimulateTxs(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	res, err0 := bc.SimulateTxs([]*chain.Transaction{
+		newTransfer(bc, aliceKey, cat, 10),
+		newTransfer(bc, catKey, bob, 10), // cat spends received coins
+	}, nil)
+	_, err1 := bc.SimulateTxs([]*chain.Transaction{
+		newTransfer(bc, aliceKey, cat, 10),
+		newTransfer(bc, catKey, bob, 11),
+	}, nil)
+
+	assert.NoError(t, err0)
+	assert.Equal(t, 2, len(res))
+	var simErr *SimulateError
+	assert.True(t, errors.As(err1, &simErr))
+	assert.Equal(t, 1, simErr.TxIdx)
+	assert.True(t, errors.Is(err1, state.ErrNegativeValue))
+}
+
+func TestChainStorage_SimulateTx_withMempool(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	err := bc.PublishTx(newTransfer(bc, aliceKey, cat, 900))
+	assert.NoError(t, err)
+	tx := newTransfer(bc, aliceKey, bob, 200)
+
+	_, err0 := bc.SimulateTx(tx, nil)
+	_, err1 := bc.SimulateTx(tx, &SimulateOptions{WithMempool: true})
+
+	assert.NoError(t, err0)
+	assert.True(t, errors.Is(err1, state.ErrNegativeValue))
+}
+
+func TestChainStorage_SimulateTx_historicalState(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 900)}, masterKey)
+	tx := newTransfer(bc, aliceKey, bob, 200)
+
+	_, err0 := bc.SimulateTx(tx, &SimulateOptions{BlockNum: 1})
+	_, err1 := bc.SimulateTx(tx, nil)
+	_, err2 := bc.SimulateTx(tx, &SimulateOptions{BlockNum: 100})
+
+	assert.NoError(t, err0)
+	assert.True(t, errors.Is(err1, state.ErrNegativeValue))
+	assert.Error(t, err2)
+}
+
+func TestChainStorage_SimulateTx_historicalRegisteredTx(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	num := bc.LastBlock().Num
+	tx := newTransfer(bc, aliceKey, cat, 10)
+	bc.PutNewBlock([]*chain.Transaction{tx}, masterKey)
+
+	_, err0 := bc.SimulateTx(tx, &SimulateOptions{BlockNum: num})
+	_, err1 := bc.SimulateTx(tx, nil)
+
+	assert.NoError(t, err0)
+	assert.True(t, errors.Is(err1, errTxHasBeenRegistered))
+}
@@ -0,0 +1,359 @@
+package bcstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/mediacoin-pro/core/crypto/merkle"
+	"github.com/mediacoin-pro/core/crypto/patricia"
+)
+
+const defaultSnapshotChunkSize = 1000
+
+var (
+	ErrInvalidSnapshot      = errors.New("bcstore: invalid state snapshot")
+	ErrSnapshotProof        = errors.New("bcstore: invalid proof of snapshot value")
+	ErrStorageIsNotEmpty    = errors.New("bcstore: storage is not empty")
+	errSnapshotHeaderLinked = errors.New("bcstore: headers are not linked by hash")
+)
+
+// Snapshot is full state of the chain at the block with transactions of the previous blocks.
+// Each value of the snapshot is proved by the state root of the block header,
+// transactions are proved by tx roots of the block headers (see: RestoreSnapshot).
+type Snapshot struct {
+	Header *chain.BlockHeader // header of the snapshot block
+	Chunks []*SnapshotChunk
+}
+
+// SnapshotChunk is a part of state values and transactions of the snapshot
+type SnapshotChunk struct {
+	Num     uint64 // num of the snapshot block
+	Entries []*SnapshotEntry
+	Blocks  []*SnapshotBlock // blocks without transactions are omitted
+}
+
+// SnapshotBlock is transactions of the block of the snapshot (ver >= 1)
+type SnapshotBlock struct {
+	Num uint64
+	Txs []*chain.Transaction
+}
+
+// SnapshotEntry is state value with patricia-proof by the state root
+type SnapshotEntry struct {
+	Asset   bin.Bytes
+	Address bin.Bytes
+	Value   bin.Bytes // bytes of balance (see: state.Value.Balance)
+	Proof   bin.Bytes
+}
+
+func (c *SnapshotChunk) Encode() []byte {
+	return bin.Encode(
+		1, // version
+		c.Num,
+		c.Entries,
+		c.Blocks, // ver >= 1
+	)
+}
+
+func (c *SnapshotChunk) Decode(data []byte) (err error) {
+	var ver int
+	buf := bin.NewBuffer(data)
+	if err = buf.ReadVar(
+		&ver,
+		&c.Num,
+		&c.Entries,
+	); err == nil && ver >= 1 {
+		err = buf.ReadVar(&c.Blocks)
+	}
+	return
+}
+
+// size returns count of values and transactions of the chunk
+func (c *SnapshotChunk) size() (n int) {
+	n = len(c.Entries)
+	for _, b := range c.Blocks {
+		n += len(b.Txs)
+	}
+	return
+}
+
+func (b *SnapshotBlock) Encode() []byte {
+	return bin.Encode(b.Num, b.Txs)
+}
+
+func (b *SnapshotBlock) Decode(data []byte) error {
+	return bin.Decode(data, &b.Num, &b.Txs)
+}
+
+func (e *SnapshotEntry) Encode() []byte {
+	return bin.Encode(e.Asset, e.Address, e.Value, e.Proof)
+}
+
+func (e *SnapshotEntry) Decode(data []byte) error {
+	return bin.Decode(data, &e.Asset, &e.Address, &e.Value, &e.Proof)
+}
+
+// stateKey returns key of the value in the state tree (see: state.Value.StateKey)
+func (e *SnapshotEntry) stateKey() []byte {
+	return append(append([]byte{}, e.Address...), e.Asset...)
+}
+
+// Verify verifies the proof of the value by the state root
+func (e *SnapshotEntry) Verify(stateRoot []byte) bool {
+	return merkle.Verify(merkle.Root(e.stateKey(), e.Value), e.Proof, stateRoot)
+}
+
+// CreateSnapshot serializes full state and transactions of the chain at the block num (num=0 - last block)
+// into chunks of chunkSize values and transactions.
+// All chunks are kept in memory; use WriteSnapshot for large states.
+func (s *ChainStorage) CreateSnapshot(num uint64, chunkSize int) (*Snapshot, error) {
+	snap := &Snapshot{}
+	header, err := s.WriteSnapshot(num, chunkSize, func(chunk *SnapshotChunk) error {
+		snap.Chunks = append(snap.Chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	snap.Header = header
+	return snap, nil
+}
+
+// WriteSnapshot streams full state of the chain at the block num (num=0 - last block) and transactions of blocks 1..num
+// by chunks of chunkSize values and transactions. It returns header of the snapshot block. Values of the last block are proved by the state tree of storage
+// (importing of blocks is locked while writing); state tree of previous block is built from the state index.
+func (s *ChainStorage) WriteSnapshot(num uint64, chunkSize int, fn func(chunk *SnapshotChunk) error) (*chain.BlockHeader, error) {
+	if chunkSize <= 0 {
+		chunkSize = defaultSnapshotChunkSize
+	}
+	s.mxW.Lock()
+	defer s.mxW.Unlock()
+
+	if num == 0 {
+		num = s.LastBlock().Num
+	}
+	header, err := s.BlockHeader(num)
+	if err != nil {
+		return nil, err
+	}
+	tree := patricia.NewTree(patricia.NewSubStorage(s.db, goldb.Key(dbTabStateTree)))
+	if num != s.LastBlock().Num {
+		tree = patricia.NewTree(nil)
+		if err = s.fetchStateAt(num, func(e *SnapshotEntry) error {
+			return tree.Put(e.stateKey(), e.Value)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if root, _ := tree.Root(); !bytes.Equal(root, header.StateRoot) {
+		return nil, errIncorrectStateRoot
+	}
+
+	chunk := &SnapshotChunk{Num: num}
+	flush := func(force bool) (err error) {
+		if n := chunk.size(); n >= chunkSize || force && n > 0 {
+			err, chunk = fn(chunk), &SnapshotChunk{Num: num}
+		}
+		return
+	}
+
+	// values of state
+	err = s.fetchStateAt(num, func(e *SnapshotEntry) (err error) {
+		if e.Value, e.Proof, _, err = tree.GetProof(e.stateKey()); err != nil {
+			return
+		}
+		chunk.Entries = append(chunk.Entries, e)
+		return flush(false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// transactions of blocks (the restored storage verifies duplicates of transactions and users by them)
+	var b *SnapshotBlock
+	err = s.db.Fetch(goldb.NewQuery(dbTabTxs), func(rec goldb.Record) error {
+		var blockNum uint64
+		var tx *chain.Transaction
+		rec.MustDecodeKey(&blockNum)
+		if blockNum > num {
+			return goldb.Break
+		}
+		if b == nil || b.Num != blockNum {
+			if b != nil {
+				if err := flush(false); err != nil {
+					return err
+				}
+			}
+			b = &SnapshotBlock{Num: blockNum}
+			chunk.Blocks = append(chunk.Blocks, b)
+		}
+		rec.MustDecode(&tx)
+		b.Txs = append(b.Txs, tx)
+		return nil
+	})
+	if err == nil {
+		err = flush(true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// fetchStateAt fetches values of state at the block by the newest records of state index for each asset and address
+func (s *ChainStorage) fetchStateAt(num uint64, fn func(e *SnapshotEntry) error) error {
+	maxTxUID := encodeTxUID(num+1, 0)
+	q := goldb.NewQuery(dbIdxAssetAddr)
+	for {
+		var asset, addr []byte
+		err := s.db.Fetch(q.Limit(1), func(rec goldb.Record) error {
+			rec.MustDecodeKey(&asset, &addr)
+			return nil
+		})
+		if err != nil {
+			return err
+		} else if asset == nil {
+			return nil
+		}
+		var v bignum.Int
+		var found bool
+		err = s.db.Fetch(goldb.NewQuery(dbIdxAssetAddr, asset, addr).Offset(maxTxUID).OrderDesc().Limit(1), func(rec goldb.Record) error {
+			rec.MustDecode(&v)
+			found = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if found {
+			if err = fn(&SnapshotEntry{Asset: asset, Address: addr, Value: v.Bytes()}); err != nil {
+				return err
+			}
+		}
+		q = goldb.NewQuery(dbIdxAssetAddr).Offset(asset, addr) // next asset and address
+	}
+}
+
+// RestoreSnapshot initializes empty storage by the snapshot; headers are headers of blocks 1..snap.Header.Num.
+// The snapshot block header is verified by VerifyHeader, previous headers are verified only by hash linkage and
+// the chain root. Values of the snapshot are verified by proofs and the state root; transactions of blocks are
+// verified by tx roots of headers and are not executed.
+// After restoring, the storage contains headers, transactions, state tree, indexes and totals of blocks,
+// so it can continue importing new blocks (see: PutBlock).
+func (s *ChainStorage) RestoreSnapshot(snap *Snapshot, headers []*chain.BlockHeader) error {
+	s.mxW.Lock()
+	defer s.mxW.Unlock()
+
+	if s.LastBlock().Num != 0 {
+		return ErrStorageIsNotEmpty
+	}
+	header := snap.Header
+	if header == nil || header.Num == 0 || uint64(len(headers)) != header.Num {
+		return ErrInvalidSnapshot
+	}
+	if !bytes.Equal(headers[len(headers)-1].Encode(), header.Encode()) {
+		return ErrInvalidSnapshot
+	}
+
+	// verify headers by hash linkage
+	pre := chain.GenesisBlockHeader(s.Cfg)
+	for i, h := range headers {
+		if h.Num != pre.Num+1 || !bytes.Equal(h.PrevHash, pre.Hash()) {
+			return fmt.Errorf("%w: block#%d", errSnapshotHeaderLinked, h.Num)
+		}
+		if i < len(headers)-1 {
+			pre = h
+		}
+	}
+	if err := header.VerifyHeader(pre, s.Cfg); err != nil {
+		return err
+	}
+
+	// verify values of snapshot by proofs; all values of state are restored
+	tree := patricia.NewTree(nil)
+	blockTxs := map[uint64][]*chain.Transaction{}
+	var lastNum uint64
+	for _, chunk := range snap.Chunks {
+		if chunk.Num != header.Num {
+			return ErrInvalidSnapshot
+		}
+		for _, e := range chunk.Entries {
+			if !e.Verify(header.StateRoot) {
+				return ErrSnapshotProof
+			}
+			if err := tree.Put(e.stateKey(), e.Value); err != nil {
+				return err
+			}
+		}
+		// verify transactions by tx roots
+		for _, b := range chunk.Blocks {
+			if b.Num <= lastNum || b.Num > header.Num || len(b.Txs) == 0 {
+				return ErrInvalidSnapshot
+			}
+			if err := chain.NewBlock(headers[b.Num-1], b.Txs).VerifyTxRoot(); err != nil {
+				return err
+			}
+			blockTxs[b.Num], lastNum = b.Txs, b.Num
+		}
+	}
+	if root, _ := tree.Root(); !bytes.Equal(root, header.StateRoot) {
+		return errIncorrectStateRoot
+	}
+	// transactions of all blocks are restored
+	for _, h := range headers {
+		if len(h.TxRoot) > 0 && blockTxs[h.Num] == nil {
+			return fmt.Errorf("%w: transactions of block#%d", ErrInvalidSnapshot, h.Num)
+		}
+	}
+
+	stat := s.stat.Clone()
+	var block *chain.Block
+	err := s.db.Exec(func(tr *goldb.Transaction) {
+
+		// restore state tree
+		stateTree := patricia.NewSubTree(tr, goldb.Key(dbTabStateTree))
+		for _, chunk := range snap.Chunks {
+			for _, e := range chunk.Entries {
+				stateTree.Put(e.stateKey(), e.Value)
+			}
+		}
+		if stateRoot, _ := stateTree.Root(); !bytes.Equal(header.StateRoot, stateRoot) {
+			tr.Fail(errIncorrectStateRoot)
+		}
+
+		// restore chain tree, headers, transactions, indexes and totals of blocks
+		chainTree := patricia.NewSubTree(tr, goldb.Key(dbTabChainTree))
+		for _, h := range headers {
+			chainTree.PutVar(h.Num, h.Hash())
+			tr.PutVar(goldb.Key(dbTabHeaders, h.Num), h)
+			block = chain.NewBlock(h, blockTxs[h.Num])
+			for txIdx, tx := range block.Txs {
+				tr.PutVar(goldb.Key(dbTabTxs, h.Num, txIdx), tx)
+				s.putTxIndexes(tr, tx, encodeTxUID(h.Num, txIdx), allIndexes)
+				stat.addTx(tx)
+			}
+			stat.addBlock(block)
+			tr.PutVar(goldb.Key(dbTabStat, h.Timestamp, h.Num), stat)
+		}
+		if chainRoot, _ := chainTree.Root(); !bytes.Equal(header.ChainRoot, chainRoot) {
+			tr.Fail(errIncorrectChainRoot)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mxR.Lock()
+	s.lastBlock = block
+	s.stat = stat
+	s.mxR.Unlock()
+
+	s.cacheHeaders.Clear()
+	s.notifySubscribers()
+	return nil
+}
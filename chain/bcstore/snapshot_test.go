@@ -0,0 +1,198 @@
+package bcstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/stretchr/testify/assert"
+)
+
+func snapshotHeaders(t *testing.T, bc *ChainStorage, num uint64) (headers []*chain.BlockHeader) {
+	err := bc.FetchBlockHeaders(0, int64(num), false, func(h *chain.BlockHeader) error {
+		headers = append(headers, h)
+		return nil
+	})
+	assert.NoError(t, err)
+	return
+}
+
+// stateBalance returns balance by state
+func stateBalance(bc *ChainStorage, addr []byte) int64 {
+	return bc.State().Get(assets.MDC, addr).Int64() / assets.Coin
+}
+
+func TestChainStorage_RestoreSnapshot(t *testing.T) {
+	src := newTestArchiveChain(t, 10)
+	defer src.Drop()
+	_, err := src.PutNewBlock([]*chain.Transaction{newTransfer(src, bobKey, alice, 5)}, masterKey)
+	assert.NoError(t, err)
+	balanceAlice, balanceBob, balanceCat := balance(src, alice), balance(src, bob), balance(src, cat)
+	for i := 0; i < 5; i++ {
+		_, err = src.PutNewBlock([]*chain.Transaction{newTransfer(src, aliceKey, cat, 1)}, masterKey)
+		assert.NoError(t, err)
+	}
+
+	snap, err := src.CreateSnapshot(11, 1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 11, snap.Header.Num)
+	assert.Equal(t, 3+11, len(snap.Chunks)) // 3 values and transactions of 11 blocks
+
+	// encoding of chunks
+	for i, chunk := range snap.Chunks {
+		c := new(SnapshotChunk)
+		assert.NoError(t, c.Decode(chunk.Encode()))
+		assert.Equal(t, chunk, c)
+		snap.Chunks[i] = c
+	}
+
+	// restore snapshot
+	bc := newTestStorage()
+	defer bc.Drop()
+	err = bc.RestoreSnapshot(snap, snapshotHeaders(t, src, 11))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 11, bc.LastBlock().Num)
+	assert.Equal(t, snap.Header.Hash(), bc.LastBlock().Hash())
+	assert.Equal(t, balanceAlice, stateBalance(bc, alice))
+	assert.Equal(t, balanceBob, stateBalance(bc, bob))
+	assert.Equal(t, balanceCat, stateBalance(bc, cat))
+
+	// continue importing blocks
+	blocks, err := src.GetBlocks(11, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(blocks))
+	err = bc.PutBlock(blocks...)
+	assert.NoError(t, err)
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+	assert.Equal(t, balance(src, alice), balance(bc, alice))
+	assert.Equal(t, balance(src, cat), balance(bc, cat))
+	assert.Equal(t, balance(src, bob), stateBalance(bc, bob))
+
+	// snapshot is restored into empty storage only
+	err = bc.RestoreSnapshot(snap, snapshotHeaders(t, src, 11))
+	assert.Equal(t, ErrStorageIsNotEmpty, err)
+}
+
+// replayContext is context of storage without registered transactions (to generate block with replayed transactions)
+type replayContext struct{ *ChainStorage }
+
+func (replayContext) TransactionByID(uint64) (*chain.Transaction, error) { return nil, nil }
+
+func TestChainStorage_RestoreSnapshot_replayedTxs(t *testing.T) {
+	src := newTestArchiveChain(t, 5)
+	defer src.Drop()
+	tx := newTransfer(src, bobKey, alice, 5)
+	_, err := src.PutNewBlock([]*chain.Transaction{tx, txobj.NewUser(src, aliceKey, "alice", 0)}, masterKey)
+	assert.NoError(t, err)
+	snap, err := src.CreateSnapshot(0, 10)
+	assert.NoError(t, err)
+
+	bc := newTestStorage()
+	defer bc.Drop()
+	err = bc.RestoreSnapshot(snap, snapshotHeaders(t, src, 6))
+	assert.NoError(t, err)
+	assert.Equal(t, src.Totals(), bc.Totals())
+	assert.Equal(t, balance(src, bob), balance(bc, bob))
+	u, err := bc.UserByNick("alice")
+	assert.NoError(t, err)
+	assert.NotNil(t, u)
+
+	// transaction of block before snapshot is replayed
+	replayed := new(chain.Transaction)
+	assert.NoError(t, replayed.Decode(tx.Encode()))
+	replayed.StateUpdates = nil
+	block, err := chain.GenerateNewBlock(replayContext{bc}, []*chain.Transaction{replayed}, masterKey)
+	assert.NoError(t, err)
+	err = bc.PutBlock(block)
+	assert.ErrorContains(t, err, errTxHasBeenRegistered.Error())
+
+	// nick of user registered before snapshot
+	block, err = chain.GenerateNewBlock(bc, []*chain.Transaction{txobj.NewUser(bc, catKey, "alice", 0)}, masterKey)
+	assert.NoError(t, err)
+	err = bc.PutBlock(block)
+	assert.ErrorContains(t, err, errUserHasBeenRegistered.Error())
+	assert.EqualValues(t, 6, bc.LastBlock().Num)
+}
+
+func TestChainStorage_RestoreSnapshot_invalid(t *testing.T) {
+	src := newTestArchiveChain(t, 5)
+	defer src.Drop()
+	headers := snapshotHeaders(t, src, 5)
+
+	restore := func(snap *Snapshot, headers []*chain.BlockHeader) error {
+		bc := newTestStorage()
+		defer bc.Drop()
+		err := bc.RestoreSnapshot(snap, headers)
+		assert.EqualValues(t, 0, bc.LastBlock().Num)
+		return err
+	}
+
+	// invalid value
+	snap, _ := src.CreateSnapshot(0, 10)
+	snap.Chunks[0].Entries[0].Value = coins(1e6).Bytes()
+	assert.Equal(t, ErrSnapshotProof, restore(snap, headers))
+
+	// missed value
+	snap, _ = src.CreateSnapshot(0, 10)
+	snap.Chunks[0].Entries = snap.Chunks[0].Entries[1:]
+	assert.Equal(t, errIncorrectStateRoot, restore(snap, headers))
+
+	// missed transactions of block
+	snap, _ = src.CreateSnapshot(0, 10)
+	last := snap.Chunks[len(snap.Chunks)-1]
+	last.Blocks = last.Blocks[:len(last.Blocks)-1]
+	assert.ErrorIs(t, restore(snap, headers), ErrInvalidSnapshot)
+
+	// invalid transaction of block
+	snap, _ = src.CreateSnapshot(0, 10)
+	last = snap.Chunks[len(snap.Chunks)-1]
+	last.Blocks[0].Txs[0].Nonce++
+	assert.Equal(t, chain.ErrInvalidTxsMerkleRoot, restore(snap, headers))
+
+	// headers are not linked
+	snap, _ = src.CreateSnapshot(0, 10)
+	invalid := append([]*chain.BlockHeader{}, headers...)
+	h := *invalid[2]
+	h.Nonce++
+	invalid[2] = &h
+	assert.ErrorIs(t, restore(snap, invalid), errSnapshotHeaderLinked)
+}
+
+func TestChainStorage_WriteSnapshot(t *testing.T) {
+	src := newTestArchiveChain(t, 5)
+	defer src.Drop()
+	_, err := src.PutNewBlock([]*chain.Transaction{newTransfer(src, bobKey, alice, 5)}, masterKey)
+	assert.NoError(t, err)
+
+	// snapshot of the last block is proved by state tree of storage
+	snap := &Snapshot{}
+	snap.Header, err = src.WriteSnapshot(0, 2, func(chunk *SnapshotChunk) error {
+		assert.True(t, len(chunk.Entries) <= 2)
+		snap.Chunks = append(snap.Chunks, chunk)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 6, snap.Header.Num)
+	for _, chunk := range snap.Chunks {
+		for _, e := range chunk.Entries {
+			assert.True(t, e.Verify(snap.Header.StateRoot))
+		}
+	}
+
+	bc := newTestStorage()
+	defer bc.Drop()
+	err = bc.RestoreSnapshot(snap, snapshotHeaders(t, src, 6))
+	assert.NoError(t, err)
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+	assert.Equal(t, balance(src, alice), stateBalance(bc, alice))
+	assert.Equal(t, balance(src, bob), stateBalance(bc, bob))
+
+	// error of writing is returned
+	errWrite := errors.New("write error")
+	_, err = src.WriteSnapshot(5, 1, func(chunk *SnapshotChunk) error {
+		return errWrite
+	})
+	assert.Equal(t, errWrite, err)
+}
@@ -1,11 +1,13 @@
 package bcstore
 
 import (
+	"github.com/mediacoin-pro/core/chain"
 	"github.com/mediacoin-pro/core/chain/assets"
 	"github.com/mediacoin-pro/core/chain/txobj"
 	"github.com/mediacoin-pro/core/common/bignum"
 	"github.com/mediacoin-pro/core/common/bin"
 	"github.com/mediacoin-pro/core/common/enc"
+	"github.com/mediacoin-pro/core/model"
 )
 
 type Statistic struct {
@@ -17,6 +19,7 @@ type Statistic struct {
 	Traffic   bignum.Int `json:"traffic"`   //
 	Rate      bignum.Int `json:"rate"`      // nanocoins for 1 GB
 	BCSize    int64      `json:"bcsize"`    //
+	Fees      bignum.Int `json:"fees"`      // total fees of transactions
 
 	_reserve2 int `json:"-"` //
 	_reserve3 int `json:"-"` //
@@ -42,7 +45,7 @@ func (s *Statistic) String() string {
 
 func (s *Statistic) Encode() []byte {
 	return bin.Encode(
-		0,
+		1, // version
 
 		s.Blocks,
 		s.Txs,
@@ -57,12 +60,16 @@ func (s *Statistic) Encode() []byte {
 		s._reserve3,
 		s._reserve4,
 		s._reserve5,
+
+		s.Fees, // ver >= 1
 	)
 }
 
-func (s *Statistic) Decode(data []byte) error {
-	return bin.Decode(data,
-		new(int), // version
+func (s *Statistic) Decode(data []byte) (err error) {
+	var ver int
+	buf := bin.NewBuffer(data)
+	if err = buf.ReadVar(
+		&ver,
 
 		&s.Blocks,
 		&s.Txs,
@@ -77,7 +84,10 @@ func (s *Statistic) Decode(data []byte) error {
 		&s._reserve3,
 		&s._reserve4,
 		&s._reserve5,
-	)
+	); err == nil && ver >= 1 {
+		err = buf.ReadVar(&s.Fees)
+	}
+	return
 }
 
 func (s *Statistic) IncrementSupplyStat(emission *txobj.Emission) {
@@ -93,3 +103,21 @@ func (s *Statistic) IncrementSupplyStat(emission *txobj.Emission) {
 		s.Traffic.Increment(bignum.NewInt(emission.TotalValue()))
 	}
 }
+
+func (s *Statistic) addTx(tx *chain.Transaction) {
+	s.Txs++
+	s.Fees.Increment(tx.Fee())
+	switch tx.Type {
+	case model.TxEmission:
+		s.IncrementSupplyStat(tx.TxObject().(*txobj.Emission))
+	case model.TxTransfer:
+		s.Transfers++
+	case model.TxUser:
+		s.Users++
+	}
+}
+
+func (s *Statistic) addBlock(block *chain.Block) {
+	s.Blocks = block.Num
+	s.BCSize += block.Size()
+}
@@ -3,6 +3,8 @@ package bcstore
 import (
 	"bytes"
 	"errors"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,8 +19,6 @@ import (
 	"github.com/mediacoin-pro/core/common/bignum"
 	"github.com/mediacoin-pro/core/common/goldb"
 	"github.com/mediacoin-pro/core/common/gosync"
-	"github.com/mediacoin-pro/core/common/safe"
-	"github.com/mediacoin-pro/core/common/xlog"
 	"github.com/mediacoin-pro/core/crypto"
 	"github.com/mediacoin-pro/core/crypto/patricia"
 	"github.com/mediacoin-pro/core/model"
@@ -34,12 +34,23 @@ type ChainStorage struct {
 	mxW          sync.Mutex
 	mxR          sync.RWMutex
 	lastBlock    *chain.Block  //
+	finalized    uint64        // num of last finalized block
 	stat         *Statistic    //
 	cacheHeaders *gosync.Cache // blockNum => *BlockHeader
 	cacheTxs     *gosync.Cache // blockNum => []*Transaction
 	cacheIdxTx   *gosync.Cache // idxKey => *Transaction
 	cacheNicks   *gosync.Cache // userID => userNick
 	middleware   []Middleware  //
+	rbMiddleware []Middleware  // middleware for reverted blocks
+
+	// header-first sync of checkpointed blocks (see: PutCheckpointHeaders)
+	cpHead   *chain.BlockHeader // last verified header
+	cpHashes [][]byte           // hashes of verified headers that are not linked to checkpoint yet
+	trusted  map[uint64][]byte  // blockNum => hash of header linked to checkpoint
+
+	// events subscriptions
+	mxSubs sync.Mutex
+	subs   map[*Subscription]struct{}
 }
 
 var _ bcclient.Client = new(ChainStorage)
@@ -53,6 +64,9 @@ const (
 	dbTabChainTree = 0x03 //
 	dbTabStateTree = 0x04 // (asset, addr) => sateValue
 	dbTabStat      = 0x05 // (ts) => Statistic
+	dbTabReindex   = 0x06 // (indexID) => next blockNum of interrupted reindex
+	dbTabCerts     = 0x07 // (blockNum) => finality.Certificate
+	dbTabReindexed = 0x08 // (indexID) => count of blocks of the last completed reindex
 
 	// indexes
 	dbIdxTxID          = 0x20 // (txID)                        => txUID
@@ -82,6 +96,25 @@ var (
 )
 
 func NewChainStorage(dir string, cfg *chain.Config) (s *ChainStorage) {
+	s = newChainStorage(dir, cfg)
+
+	// upgrade db-schema
+	s.migrate()
+
+	// resume interrupted reindex
+	if err := s.resumeReindex(); err != nil {
+		panic(err)
+	}
+
+	// load unconfirmed txs (txs are verified by indexes)
+	if err := s.Mempool.OpenJournal(s.mempoolJournalFile()); err != nil {
+		panic(err)
+	}
+
+	return
+}
+
+func newChainStorage(dir string, cfg *chain.Config) (s *ChainStorage) {
 	if cfg == nil {
 		cfg = chain.NewConfig()
 	}
@@ -93,8 +126,9 @@ func NewChainStorage(dir string, cfg *chain.Config) (s *ChainStorage) {
 		cacheTxs:     gosync.NewCache(1000),
 		cacheIdxTx:   gosync.NewCache(50000),
 		cacheNicks:   gosync.NewCache(10000),
-		Mempool:      mempool.NewStorage(),
+		trusted:      map[uint64][]byte{},
 	}
+	s.Mempool = mempool.NewStorage(s, nil)
 
 	//if cfg.VacuumDB {
 	//	s.db.Vacuum()
@@ -106,6 +140,12 @@ func NewChainStorage(dir string, cfg *chain.Config) (s *ChainStorage) {
 	} else {
 		s.lastBlock = b
 	}
+	// query last finalized block
+	if num, err := s.queryFinalizedNum(); err != nil {
+		panic(err)
+	} else {
+		s.finalized = num
+	}
 	// query actual totals
 	if st, err := s.TotalsAt(time.Time{}); err != nil {
 		panic(err)
@@ -120,7 +160,8 @@ func NewChainStorage(dir string, cfg *chain.Config) (s *ChainStorage) {
 	// set default user-name resolver
 	chain.UserNameByID = s.UsernameByID
 
-	go s.reindex()
+	// set default resolver of custom assets
+	assets.InfoByID = s.AssetInfo
 
 	return
 }
@@ -130,18 +171,39 @@ func (s *ChainStorage) Config() *chain.Config {
 }
 
 func (s *ChainStorage) Close() (err error) {
+	s.Mempool.CloseJournal()
 	return s.db.Close()
 }
 
 func (s *ChainStorage) Drop() (err error) {
+	s.Mempool.CloseJournal()
+	os.Remove(s.mempoolJournalFile())
 	s.db.Close()
 	return s.db.Drop()
 }
 
+// mempoolJournalFile returns path of journal of unconfirmed transactions
+func (s *ChainStorage) mempoolJournalFile() string {
+	return filepath.Clean(s.Dir) + ".mempool"
+}
+
 func (s *ChainStorage) Dump(filePath string) (err error) {
 	return s.db.Dump(filePath, nil)
 }
 
+// RestoreDump creates new storage in the dir from the dump file (see: Dump)
+func RestoreDump(filePath, dir string, cfg *chain.Config) (*ChainStorage, error) {
+	db := goldb.NewStorage(dir, nil)
+	if err := db.Restore(filePath); err != nil {
+		db.Drop()
+		return nil, err
+	}
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	return NewChainStorage(dir, cfg), nil
+}
+
 func (s *ChainStorage) VacuumDB() error {
 	return s.db.Vacuum()
 }
@@ -150,6 +212,11 @@ func (s *ChainStorage) AddMiddleware(fn Middleware) {
 	s.middleware = append(s.middleware, fn)
 }
 
+// AddRollbackMiddleware adds handler that is called for each reverted block (see: RollbackTo)
+func (s *ChainStorage) AddRollbackMiddleware(fn Middleware) {
+	s.rbMiddleware = append(s.rbMiddleware, fn)
+}
+
 func (s *ChainStorage) DB() *goldb.Storage {
 	return s.db
 }
@@ -176,6 +243,11 @@ func (s *ChainStorage) State() *state.State {
 	})
 }
 
+// AssetInfo returns metadata of custom asset (nil if the asset is not defined)
+func (s *ChainStorage) AssetInfo(asset []byte) *assets.Info {
+	return s.State().AssetInfo(asset)
+}
+
 // ----------------- put block --------------------------
 func (s *ChainStorage) PutNewBlock(txs []*chain.Transaction, miner *crypto.PrivateKey) (block *chain.Block, err error) {
 	block, err = chain.GenerateNewBlock(s, txs, miner)
@@ -195,16 +267,18 @@ func (s *ChainStorage) PutBlock(blocks ...*chain.Block) error {
 	s.mxW.Lock()
 	defer s.mxW.Unlock()
 
+	return s.putBlocks(blocks)
+}
+
+func (s *ChainStorage) putBlocks(blocks []*chain.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
 	// verify blocks
-	lastBlockHeader := s.lastBlock.BlockHeader
-	for _, block := range blocks {
-		for txIdx, tx := range block.Txs {
-			tx.SetBlockInfo(s, block.Num, txIdx, block.Timestamp)
-		}
-		if err := block.Verify(lastBlockHeader, s.Cfg); err != nil {
-			return err
-		}
-		lastBlockHeader = block.BlockHeader
+	trusted := s.trustedBlocks(blocks)
+	if err := s.verifyBlocks(blocks, trusted); err != nil {
+		return err
 	}
 
 	var stat = s.stat.Clone()
@@ -217,7 +291,12 @@ func (s *ChainStorage) PutBlock(blocks ...*chain.Block) error {
 		stateTree := patricia.NewSubTree(tr, goldb.Key(dbTabStateTree))
 		chainTree := patricia.NewSubTree(tr, goldb.Key(dbTabChainTree))
 
-		for _, block := range blocks {
+		for i, block := range blocks {
+
+			// verify miner of block by validators set
+			if err := block.VerifyMiner(txCtx); err != nil {
+				tr.Fail(err)
+			}
 
 			// init new block statistic
 			//blockStat = blockStat.New(block.Num, len(block.Txs))
@@ -234,10 +313,11 @@ func (s *ChainStorage) PutBlock(blocks ...*chain.Block) error {
 					tr.Fail(errTxHasBeenRegistered)
 				}
 
-				if s.Cfg.VerifyTxsLevel >= chain.VerifyTxLevel1 {
+				if s.Cfg.VerifyTxsLevel >= chain.VerifyTxLevel1 && i >= trusted {
 
 					//-- set tx context
 					tx.SetBlockInfo(txCtx, block.Num, txIdx, block.Timestamp)
+					tx.SetBlockMiner(block.Miner)
 
 					//-- verify sender signature
 					if err := tx.Verify(); err != nil {
@@ -256,87 +336,37 @@ func (s *ChainStorage) PutBlock(blocks ...*chain.Block) error {
 						tr.Fail(errIncorrectTxState)
 					}
 					txCtx.State().Apply(stateUpdates)
+				} else {
+					// state of the block is trusted (verified by state root only)
+					txCtx.State().Apply(tx.StateUpdates)
 				}
 
-				stat.Txs++
-
-				obj := tx.TxObject()
-
-				switch tx.Type {
-
-				case model.TxEmission:
-					emission := obj.(*txobj.Emission)
-
-					//if emission.IsPrimaryEmission() {
-					//	for _, out := range emission.Outs {
-					//		// set last tx by source
-					//		tr.Put(goldb.Key(dbIdxSourceTx, emission.Asset, out.SourceID, txUID), nil)
-					//
-					//		// increment last tx by source
-					//		if out.Delta > 0 {
-					//			delta := emission.Amount(out.Delta)
-					//			tr.IncrementBig(goldb.Key(dbIdxSourceAddr, emission.Asset, out.SourceID, out.Address), delta.BigInt())
-					//		}
-					//	}
-					//}
-					// else if emission.IsReferralReward() {
-					//	for _, out := range emission.Outs {
-					//		if out.Delta > 0 {
-					//			delta := emission.Amount(out.Delta)
-					//			tr.IncrementBig(goldb.Key(dbIdxSrcInvites, emission.Asset, out.SourceID, out.Address), delta.BigInt())
-					//		}
-					//	}
-
-					stat.IncrementSupplyStat(emission) // refresh totals statistic
-
-				case model.TxTransfer:
-					//tr := obj.(*txobj.Transfer)
-					//stat.IncVolumeStat(tr) // refresh statistic of total transfers
-					stat.Transfers++
-
-				case model.TxUser:
-					//userID := tx.Sender.ID()
-					usr := obj.(*txobj.User)
-					userID := usr.UserID()
+				if tx.Type == model.TxUser {
+					usr := tx.TxObject().(*txobj.User)
 
 					// get user by userID
-					if usrTxUID, _ := tr.GetID(goldb.Key(dbIdxUserID, userID)); usrTxUID != 0 {
+					if usrTxUID, _ := tr.GetID(goldb.Key(dbIdxUserID, usr.UserID())); usrTxUID != 0 {
 						tr.Fail(errUserHasBeenRegistered)
 					}
-					tr.PutID(goldb.Key(dbIdxUserID, userID), txUID)
-
 					// get user by nick
 					if usrTxUID, _ := tr.GetID(goldb.Key(dbIdxUserNick, usr.Nick)); usrTxUID != 0 {
 						tr.Fail(errUserHasBeenRegistered)
 					}
-					tr.PutID(goldb.Key(dbIdxUserNick, usr.Nick), txUID)
-
-					// referrals
-					if usr.ReferrerID != 0 {
-						tr.PutID(goldb.Key(dbIdxInvites, usr.ReferrerID, txUID), txUID)
-					}
-
-					// increment users counter
-					stat.Users++
 				}
 
+				// refresh totals statistic
+				stat.addTx(tx)
+
 				// put transaction data
 				tr.PutVar(goldb.Key(dbTabTxs, block.Num, txIdx), tx)
 
-				// put index transaction by txID
-				tr.PutID(goldb.Key(dbIdxTxID, txID), txUID)
+				// put indexes of transaction
+				s.putTxIndexes(tr, tx, txUID, allIndexes)
 
 				// save state to db-storage
-				for stIdx, v := range tx.StateUpdates {
+				for _, v := range tx.StateUpdates {
 					if v.ChainID == s.Cfg.ChainID {
 						stateTree.Put(v.StateKey(), v.Balance.Bytes())
-
-						tr.PutVar(goldb.Key(dbIdxAssetAddr, v.Asset, v.Address, txUID, stIdx), v.Balance)
-						tr.PutVar(goldb.Key(dbIdxTypeAssetAddr, tx.Type, v.Asset, v.Address, txUID), 0)
-
-						if v.Memo != 0 { // change state with memo
-							tr.PutVar(goldb.Key(dbIdxAssetAddrMemo, v.Asset, v.Address, v.Memo, txUID, stIdx), v.Balance)
-						}
 					}
 				}
 			}
@@ -356,8 +386,7 @@ func (s *ChainStorage) PutBlock(blocks ...*chain.Block) error {
 			tr.PutVar(goldb.Key(dbTabHeaders, block.Num), block.BlockHeader)
 
 			// save totals
-			stat.Blocks = block.Num
-			stat.BCSize += block.Size()
+			stat.addBlock(block)
 			tr.PutVar(goldb.Key(dbTabStat, block.Timestamp, block.Num), stat)
 
 			// middleware for each block
@@ -381,11 +410,15 @@ func (s *ChainStorage) PutBlock(blocks ...*chain.Block) error {
 
 	for _, block := range blocks {
 		s.cacheHeaders.Set(block.Num, block.BlockHeader)
+		delete(s.trusted, block.Num)
 	}
 
 	// remove txs from Mempool
 	s.Mempool.RemoveTx(txsIDs...)
 
+	// deliver events to subscribers
+	s.notifySubscribers()
+
 	return nil
 }
 
@@ -1001,35 +1034,68 @@ func (s *ChainStorage) AddressInfo(addr []byte, memo uint64, asset []byte) (inf
 	return
 }
 
-func (s *ChainStorage) reindex() {
-	defer safe.RecoverAndReport()
-	time.Sleep(10 * time.Second)
+// GetBalanceAt returns balance of address (and last transaction) after execution of the block blockNum
+func (s *ChainStorage) GetBalanceAt(addr, asset []byte, memo uint64, blockNum uint64) (balance bignum.Int, lastTx *chain.Transaction, err error) {
+	lastTx, balance, err = s.QueryTransaction(asset, addr, memo, encodeTxUID(blockNum+1, 0), true)
+	return
+}
 
-	if n, _ := s.db.GetNumRows(goldb.NewQuery(dbIdxTypeAssetAddr).Limit(1)); n > 0 {
+// GetBalanceAtTime returns balance of address (and last transaction) at the time t
+func (s *ChainStorage) GetBalanceAtTime(addr, asset []byte, memo uint64, t time.Time) (balance bignum.Int, lastTx *chain.Transaction, err error) {
+	st, err := s.TotalsAt(t)
+	if err != nil {
 		return
 	}
-	var blockNum = uint64(2) // start from second block
-	var txUID = blockNum << 32
-	countBlocks := s.CountBlocks()
-	xlog.Printf("- start reindexer   blocks:%d", countBlocks)
-	defer xlog.Printf("- FINISH reindexer   blocks:%d ", blockNum)
-
-	for i := 0; blockNum < countBlocks; {
-		time.Sleep(time.Millisecond)
-		func() {
-			s.mxW.Lock()
-			defer s.mxW.Unlock()
-			s.db.Exec(func(tr *goldb.Transaction) {
-				s.FetchTransactions(txUID, 5000, false, func(tx *chain.Transaction) error {
-					i++
-					txUID, blockNum = tx.TxUID(), tx.BlockNum()
-					for _, v := range tx.StateUpdates {
-						tr.PutVar(goldb.Key(dbIdxTypeAssetAddr, tx.Type, v.Asset, v.Address, txUID), 0)
-					}
-					return nil
-				})
-			})
-			xlog.Printf("- reindexed  txs:%d  blocks:%d/%d", i, blockNum, countBlocks)
-		}()
+	return s.GetBalanceAt(addr, asset, memo, st.Blocks)
+}
+
+// BalancesAt returns all non-zero balances of the asset after execution of the block blockNum
+func (s *ChainStorage) BalancesAt(asset []byte, blockNum uint64) (balances []*chain.AddressInfo, err error) {
+	err = s.FetchBalancesAt(asset, blockNum, func(inf *chain.AddressInfo) error {
+		balances = append(balances, inf)
+		return nil
+	})
+	return
+}
+
+// FetchBalancesAt fetches all non-zero balances of the asset (ordered by address) after execution of the block blockNum
+func (s *ChainStorage) FetchBalancesAt(asset []byte, blockNum uint64, fn func(inf *chain.AddressInfo) error) error {
+	if len(asset) == 0 {
+		asset = assets.Default
+	}
+	var (
+		maxTxUID = encodeTxUID(blockNum+1, 0)
+		addr     []byte
+		txUID    uint64
+		last     *chain.AddressInfo
+		err      error
+	)
+	flush := func() {
+		if last != nil && !last.Balance.IsZero() {
+			err = fn(last)
+		}
+	}
+	fetchErr := s.db.Fetch(goldb.NewQuery(dbIdxAssetAddr, asset), func(rec goldb.Record) error {
+		if rec.MustDecodeKey(new([]byte), &addr, &txUID); txUID >= maxTxUID {
+			return nil
+		}
+		if last == nil || !bytes.Equal(last.Address, addr) {
+			if flush(); err != nil {
+				return goldb.Break
+			}
+			last = &chain.AddressInfo{Address: addr, Asset: asset}
+		}
+		rec.MustDecode(&last.Balance)
+		return nil
+	})
+	if fetchErr != nil {
+		return fetchErr
+	}
+	if err == nil {
+		flush()
+	}
+	if err == goldb.Break {
+		err = nil
 	}
+	return err
 }
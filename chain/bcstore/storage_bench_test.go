@@ -0,0 +1,64 @@
+package bcstore
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+)
+
+func BenchmarkChainStorage_PutBlock_1Worker(b *testing.B) {
+	benchmarkPutBlocks(b, 1)
+}
+
+func BenchmarkChainStorage_PutBlock_4Workers(b *testing.B) {
+	benchmarkPutBlocks(b, 4)
+}
+
+func BenchmarkChainStorage_PutBlock_NumCPUWorkers(b *testing.B) {
+	benchmarkPutBlocks(b, runtime.NumCPU())
+}
+
+var benchBlocks [][]byte
+
+// benchmarkPutBlocks puts batch of 10 blocks (100 transfers per block)
+func benchmarkPutBlocks(b *testing.B, workers int) {
+	if benchBlocks == nil {
+		src := newTestChain(b)
+		for i := 0; i < 10; i++ {
+			var txs []*chain.Transaction
+			for j := 0; j < 100; j++ {
+				txs = append(txs, newTransfer(src, aliceKey, cat, 1))
+			}
+			src.PutNewBlock(txs, masterKey)
+		}
+		src.FetchBlocks(0, 100, false, func(block *chain.Block) error {
+			benchBlocks = append(benchBlocks, block.Encode())
+			return nil
+		})
+		src.Drop()
+	}
+	cfg := newTestConfig()
+	cfg.VerifyTxsWorkers = workers
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		bc := newTestStorage()
+		bc.Cfg = cfg
+		blocks := make([]*chain.Block, len(benchBlocks))
+		for i, data := range benchBlocks {
+			blocks[i] = new(chain.Block)
+			blocks[i].Decode(data)
+		}
+		b.StartTimer()
+
+		if err := bc.PutBlock(blocks...); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		bc.Drop()
+		b.StartTimer()
+	}
+}
@@ -0,0 +1,150 @@
+package bcstore
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	masterKey = crypto.NewPrivateKey()
+	aliceKey  = crypto.NewPrivateKey()
+	bobKey    = crypto.NewPrivateKey()
+	catKey    = crypto.NewPrivateKey()
+
+	alice = aliceKey.PublicKey().Address()
+	bob   = bobKey.PublicKey().Address()
+	cat   = catKey.PublicKey().Address()
+)
+
+func newTestConfig() *chain.Config {
+	cfg := chain.NewConfig()
+	cfg.MasterKey = masterKey.PublicKey().String()
+	return cfg
+}
+
+func newTestStorage() *ChainStorage {
+	return NewChainStorage(fmt.Sprintf("%s/test-bcstore-%x.db", os.TempDir(), rand.Int()), newTestConfig())
+}
+
+// newTestChain returns storage with genesis emission (block#1)
+func newTestChain(t testing.TB) *ChainStorage {
+	bc := newTestStorage()
+	_, err := bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewEmission(bc, masterKey, assets.MDC, "", []*txobj.EmissionOutput{
+			{Address: alice, Amount: coins(1000)},
+			{Address: bob, Amount: coins(1000)},
+		}),
+	}, masterKey)
+	assert.NoError(t, err)
+	return bc
+}
+
+func coins(n int64) bignum.Int {
+	return bignum.NewInt(n * assets.Coin)
+}
+
+func newTransfer(bc chain.BCContext, from *crypto.PrivateKey, to []byte, amount int64) *chain.Transaction {
+	return txobj.NewSimpleTransfer(bc, from.PublicKey(), from, assets.MDC, coins(amount), 0, to, 0, "", 0)
+}
+
+func balance(bc *ChainStorage, addr []byte) int64 {
+	v, _, _ := bc.GetBalance(addr, assets.MDC)
+	return v.Int64() / assets.Coin
+}
+
+func TestChainStorage_PutNewBlock(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+
+	block, err := bc.PutNewBlock([]*chain.Transaction{
+		newTransfer(bc, aliceKey, cat, 10),
+		newTransfer(bc, bobKey, cat, 20),
+	}, masterKey)
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, block.Num)
+	assert.EqualValues(t, 2, bc.LastBlock().Num)
+	assert.EqualValues(t, 990, balance(bc, alice))
+	assert.EqualValues(t, 980, balance(bc, bob))
+	assert.EqualValues(t, 30, balance(bc, cat))
+	assert.EqualValues(t, 3, bc.Totals().Txs)
+}
+
+func TestChainStorage_GetBalanceAt(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 10)}, masterKey)
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 20)}, masterKey)
+	block2, _ := bc.BlockHeader(2)
+
+	bal0, tx0, err0 := bc.GetBalanceAt(cat, assets.MDC, 0, 1)
+	bal2, tx2, err2 := bc.GetBalanceAt(cat, assets.MDC, 0, 2)
+	bal3, tx3, err3 := bc.GetBalanceAt(cat, assets.MDC, 0, 100)
+	balT, _, errT := bc.GetBalanceAtTime(alice, assets.MDC, 0, time.UnixMicro(block2.Timestamp+1))
+
+	assert.NoError(t, err0)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.NoError(t, errT)
+	assert.True(t, bal0.IsZero())
+	assert.Nil(t, tx0)
+	assert.EqualValues(t, 10*assets.Coin, bal2.Int64())
+	assert.EqualValues(t, 2, tx2.BlockNum())
+	assert.EqualValues(t, 30*assets.Coin, bal3.Int64())
+	assert.EqualValues(t, 3, tx3.BlockNum())
+	assert.EqualValues(t, 990*assets.Coin, balT.Int64())
+}
+
+func TestChainStorage_BalancesAt(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, cat, 10)}, masterKey)
+	bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, catKey, bob, 10)}, masterKey)
+
+	balances1, err1 := bc.BalancesAt(assets.MDC, 1)
+	balances2, err2 := bc.BalancesAt(assets.MDC, 2)
+	balances3, err3 := bc.BalancesAt(assets.MDC, 3)
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.Equal(t, map[string]int64{crypto.EncodeAddress(alice): 1000, crypto.EncodeAddress(bob): 1000}, balancesMap(balances1))
+	assert.Equal(t, map[string]int64{crypto.EncodeAddress(alice): 990, crypto.EncodeAddress(bob): 1000, crypto.EncodeAddress(cat): 10}, balancesMap(balances2))
+	assert.Equal(t, map[string]int64{crypto.EncodeAddress(alice): 990, crypto.EncodeAddress(bob): 1010}, balancesMap(balances3))
+}
+
+func balancesMap(balances []*chain.AddressInfo) map[string]int64 {
+	m := map[string]int64{}
+	for _, inf := range balances {
+		m[crypto.EncodeAddress(inf.Address)] = inf.Balance.Int64() / assets.Coin
+	}
+	return m
+}
+
+func TestChainStorage_Mempool_restart(t *testing.T) {
+	bc := newTestChain(t)
+	tx1 := newTransfer(bc, aliceKey, cat, 1)
+	tx2 := newTransfer(bc, bobKey, cat, 2)
+	err := bc.PublishTx(tx1)
+	assert.NoError(t, err)
+	bc.PublishTx(tx2)
+	bc.PutNewBlock([]*chain.Transaction{tx2}, masterKey) // tx2 is confirmed
+	bc.Close()
+
+	bc = NewChainStorage(bc.Dir, bc.Cfg)
+	defer bc.Drop()
+
+	txs, _ := bc.Mempool.AllTxs()
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, tx1.ID(), txs[0].ID())
+}
@@ -0,0 +1,157 @@
+package bcstore
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	validator1 = crypto.NewPrivateKey()
+	validator2 = crypto.NewPrivateKey()
+	validator3 = crypto.NewPrivateKey()
+)
+
+// newTestPoAChain returns storage with PoA-consensus activated from block#3; validators set is {validator1, validator2}
+func newTestPoAChain(t *testing.T) *ChainStorage {
+	cfg := newTestConfig()
+	cfg.PoAHeight = 3
+	bc := NewChainStorage(fmt.Sprintf("%s/test-bcstore-%x.db", os.TempDir(), rand.Int()), cfg)
+	_, err := bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewEmission(bc, masterKey, assets.MDC, "", []*txobj.EmissionOutput{
+			{Address: alice, Amount: coins(1000)},
+		}),
+	}, masterKey)
+	assert.NoError(t, err)
+
+	add := []*crypto.PublicKey{validator1.PublicKey(), validator2.PublicKey()}
+	remove := []*crypto.PublicKey{masterKey.PublicKey()}
+	_, err = bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewValidatorsUpd(bc, nil, aliceKey, add, remove, []*txobj.ValidatorSig{
+			txobj.SignValidatorsUpd(bc, add, remove, masterKey),
+		}),
+	}, masterKey)
+	assert.NoError(t, err)
+	return bc
+}
+
+func TestChainStorage_PoA(t *testing.T) {
+	bc := newTestPoAChain(t)
+	defer bc.Drop()
+
+	assert.Equal(t, []*crypto.PublicKey{validator1.PublicKey(), validator2.PublicKey()}, chain.Validators(bc))
+	assert.Equal(t, validator2.PublicKey(), chain.ScheduledMiner(bc, 3))
+	assert.Equal(t, validator1.PublicKey(), chain.ScheduledMiner(bc, 4))
+
+	_, err0 := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, bob, 1)}, masterKey)
+	_, err1 := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, bob, 1)}, validator1)
+	_, err2 := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, bob, 1)}, validator2)
+	_, err3 := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, aliceKey, bob, 1)}, validator1)
+
+	assert.ErrorContains(t, err0, chain.ErrNotScheduledMiner.Error())
+	assert.ErrorContains(t, err1, chain.ErrNotScheduledMiner.Error())
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.EqualValues(t, 4, bc.CountBlocks())
+	assert.EqualValues(t, 2, balance(bc, bob))
+
+	rep, err := bc.VerifyChain()
+	assert.NoError(t, err)
+	assert.True(t, rep.OK())
+}
+
+func TestChainStorage_PoA_beforeActivation(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PoAHeight = 3
+	bc := NewChainStorage(fmt.Sprintf("%s/test-bcstore-%x.db", os.TempDir(), rand.Int()), cfg)
+	defer bc.Drop()
+
+	_, err := bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewEmission(bc, masterKey, assets.MDC, "", []*txobj.EmissionOutput{
+			{Address: alice, Amount: coins(1000)},
+		}),
+	}, validator1)
+
+	assert.ErrorContains(t, err, chain.ErrInvalidMinerKey.Error())
+}
+
+func TestChainStorage_PoA_validatorsUpd(t *testing.T) {
+	bc := newTestPoAChain(t)
+	defer bc.Drop()
+	add := []*crypto.PublicKey{validator3.PublicKey()}
+
+	// quorum of 2 validators is 2 signatures
+	_, err0 := bc.SimulateTx(txobj.NewValidatorsUpd(bc, nil, aliceKey, add, nil, []*txobj.ValidatorSig{
+		txobj.SignValidatorsUpd(bc, add, nil, validator1),
+	}), nil)
+	_, err1 := bc.SimulateTx(txobj.NewValidatorsUpd(bc, nil, aliceKey, add, nil, []*txobj.ValidatorSig{
+		txobj.SignValidatorsUpd(bc, add, nil, validator1),
+		txobj.SignValidatorsUpd(bc, add, nil, validator1),
+	}), nil)
+	_, err2 := bc.SimulateTx(txobj.NewValidatorsUpd(bc, nil, aliceKey, add, nil, []*txobj.ValidatorSig{
+		txobj.SignValidatorsUpd(bc, add, nil, validator1),
+		txobj.SignValidatorsUpd(bc, add, nil, validator3),
+	}), nil)
+	block, err3 := bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewValidatorsUpd(bc, nil, aliceKey, add, nil, []*txobj.ValidatorSig{
+			txobj.SignValidatorsUpd(bc, add, nil, validator1),
+			txobj.SignValidatorsUpd(bc, add, nil, validator2),
+		}),
+	}, validator2)
+
+	assert.True(t, errors.Is(err0, txobj.ErrTxNoQuorum))
+	assert.True(t, errors.Is(err1, txobj.ErrTxNoQuorum))
+	assert.True(t, errors.Is(err2, txobj.ErrTxNoQuorum))
+	assert.NoError(t, err3)
+	assert.NotNil(t, block)
+	assert.Equal(t, 3, len(chain.Validators(bc)))
+	assert.Equal(t, validator2.PublicKey(), chain.ScheduledMiner(bc, 4))
+}
+
+func TestChainStorage_PoA_validatorsUpd_replay(t *testing.T) {
+	bc := newTestPoAChain(t)
+	defer bc.Drop()
+	add := []*crypto.PublicKey{validator3.PublicKey()}
+	sigsAdd := []*txobj.ValidatorSig{
+		txobj.SignValidatorsUpd(bc, add, nil, validator1),
+		txobj.SignValidatorsUpd(bc, add, nil, validator2),
+	}
+	_, err := bc.PutNewBlock([]*chain.Transaction{txobj.NewValidatorsUpd(bc, nil, aliceKey, add, nil, sigsAdd)}, validator2)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, bc.State().ValidatorsEpoch())
+
+	// validators set returns to previous configuration
+	_, err = bc.PutNewBlock([]*chain.Transaction{txobj.NewValidatorsUpd(bc, nil, aliceKey, nil, add, []*txobj.ValidatorSig{
+		txobj.SignValidatorsUpd(bc, nil, add, validator1),
+		txobj.SignValidatorsUpd(bc, nil, add, validator2),
+		txobj.SignValidatorsUpd(bc, nil, add, validator3),
+	})}, scheduledValidator(bc))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(chain.Validators(bc)))
+
+	// old signatures can not be replayed with current or old epoch
+	_, err1 := bc.SimulateTx(txobj.NewValidatorsUpd(bc, nil, bobKey, add, nil, sigsAdd), nil)
+	old := &txobj.ValidatorsUpd{Add: add, Sigs: sigsAdd, Epoch: 1}
+	_, err2 := bc.SimulateTx(chain.NewTx(bc, nil, bobKey, 0, old), nil)
+
+	assert.True(t, errors.Is(err1, txobj.ErrTxNoQuorum))
+	assert.True(t, errors.Is(err2, txobj.ErrTxIncorrectEpoch))
+}
+
+func scheduledValidator(bc *ChainStorage) *crypto.PrivateKey {
+	miner := chain.ScheduledMiner(bc, bc.LastBlock().Num+1)
+	for _, prv := range []*crypto.PrivateKey{validator1, validator2, validator3} {
+		if prv.PublicKey().Equal(miner) {
+			return prv
+		}
+	}
+	return nil
+}
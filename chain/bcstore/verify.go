@@ -0,0 +1,369 @@
+package bcstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/mediacoin-pro/core/common/hex"
+	"github.com/mediacoin-pro/core/crypto/patricia"
+	"github.com/mediacoin-pro/core/model"
+)
+
+// VerifyReport is result of verification of chain storage
+type VerifyReport struct {
+	Blocks       uint64        `json:"blocks"`        // count of verified blocks
+	Txs          int64         `json:"txs"`           // count of verified transactions
+	InvalidBlock uint64        `json:"invalid_block"` // number of first divergent block (0 if blocks are valid)
+	BlockError   string        `json:"block_error"`   // error of first divergent block
+	IndexErrors  []*IndexError `json:"index_errors"`  // inconsistencies of secondary indexes
+}
+
+type IndexError struct {
+	Index IndexID   `json:"index"`
+	Key   hex.Bytes `json:"key"`   // db-key of index record
+	Error string    `json:"error"` //
+}
+
+var (
+	errIdxRecordNotFound = errors.New("index record not found")
+	errIdxInvalidValue   = errors.New("invalid value of index record")
+	errIdxInvalidRef     = errors.New("index record refers to invalid data")
+)
+
+// OK returns true if blocks and indexes are consistent
+func (r *VerifyReport) OK() bool {
+	return r.InvalidBlock == 0 && len(r.IndexErrors) == 0
+}
+
+func (r *VerifyReport) String() string {
+	if r.OK() {
+		return fmt.Sprintf("OK (blocks:%d, txs:%d)", r.Blocks, r.Txs)
+	}
+	return fmt.Sprintf("FAIL (blocks:%d, txs:%d, invalid block:%d %s, index errors:%d)", r.Blocks, r.Txs, r.InvalidBlock, r.BlockError, len(r.IndexErrors))
+}
+
+func (r *VerifyReport) addIndexError(id IndexID, key []byte, err error) {
+	r.IndexErrors = append(r.IndexErrors, &IndexError{id, key, err.Error()})
+}
+
+// VerifyDir verifies chain storage in the directory (storage must be closed)
+func VerifyDir(dir string, cfg *chain.Config) (*VerifyReport, error) {
+	s := newChainStorage(dir, cfg)
+	defer s.Close()
+	if _, err := s.Migrate(&goldb.MigrateOptions{DryRun: true}); err != nil { // check schema version
+		return nil, err
+	}
+	return s.VerifyChain()
+}
+
+// VerifyChain replays all stored blocks: verifies headers, transactions, state and chain roots.
+// Secondary indexes are cross-checked against blocks data.
+// The blocks committed after start of verification are not verified.
+func (s *ChainStorage) VerifyChain() (r *VerifyReport, err error) {
+	r = &VerifyReport{}
+	lastNum := s.LastBlock().Num
+
+	v := &chainVerifier{
+		ChainStorage: s,
+		balances:     map[string]bignum.Int{},
+		stateTree:    patricia.NewTree(nil),
+		chainTree:    patricia.NewTree(nil),
+		stat:         &Statistic{},
+		report:       r,
+	}
+	v.lastHeader = chain.GenesisBlockHeader(s.Cfg)
+
+	// replay blocks
+	for num := uint64(1); num <= lastNum && r.InvalidBlock == 0; num++ {
+		block, err := v.readBlock(num)
+		if err == nil {
+			err = v.verifyBlock(block)
+		}
+		if err != nil {
+			r.InvalidBlock, r.BlockError = num, err.Error()
+		} else {
+			r.Blocks++
+			r.Txs += int64(len(block.Txs))
+		}
+	}
+
+	// verify references of index records
+	err = v.verifyIndexRefs(lastNum)
+	return
+}
+
+type chainVerifier struct {
+	*ChainStorage
+	lastHeader *chain.BlockHeader
+	balances   map[string]bignum.Int
+	stateTree  *patricia.Tree
+	chainTree  *patricia.Tree
+	stat       *Statistic
+	report     *VerifyReport
+}
+
+func (v *chainVerifier) LastBlockHeader() *chain.BlockHeader {
+	return v.lastHeader
+}
+
+func (v *chainVerifier) State() *state.State {
+	return state.NewState(v.Cfg.ChainID, func(asset, addr []byte) bignum.Int {
+		return v.balances[string(asset)+string(addr)]
+	})
+}
+
+func (v *chainVerifier) StateTree() *patricia.Tree {
+	return v.stateTree
+}
+
+func (v *chainVerifier) ChainTree() *patricia.Tree {
+	return v.chainTree
+}
+
+// readBlock reads block from db-storage (without caches)
+func (v *chainVerifier) readBlock(num uint64) (block *chain.Block, err error) {
+	h := new(chain.BlockHeader)
+	if ok, err := v.db.GetVar(goldb.Key(dbTabHeaders, num), h); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrBlockNotFound
+	}
+	var txs []*chain.Transaction
+	err = v.db.Fetch(goldb.NewQuery(dbTabTxs, num), func(rec goldb.Record) error {
+		var tx *chain.Transaction
+		rec.MustDecode(&tx)
+		txs = append(txs, tx)
+		return nil
+	})
+	return chain.NewBlock(h, txs), err
+}
+
+func (v *chainVerifier) verifyBlock(block *chain.Block) error {
+	for txIdx, tx := range block.Txs {
+		tx.SetBlockInfo(v, block.Num, txIdx, block.Timestamp)
+	}
+
+	// verify header and txs root
+	if err := block.Verify(v.lastHeader, v.Cfg); err != nil {
+		return err
+	}
+	if err := block.VerifyMiner(v); err != nil {
+		return err
+	}
+
+	txCtx := chain.NewSubContext(v)
+	for txIdx, tx := range block.Txs {
+		tx.SetBlockInfo(txCtx, block.Num, txIdx, block.Timestamp)
+		tx.SetBlockMiner(block.Miner)
+		if err := tx.Verify(); err != nil {
+			return err
+		}
+		stateUpdates, err := tx.Execute()
+		if err != nil {
+			return err
+		}
+		if !tx.StateUpdates.Equal(stateUpdates) {
+			return errIncorrectTxState
+		}
+		txCtx.State().Apply(stateUpdates)
+		v.stat.addTx(tx)
+		v.verifyTxIndexes(tx, encodeTxUID(block.Num, txIdx))
+	}
+
+	// apply state
+	for _, tx := range block.Txs {
+		for _, u := range tx.StateUpdates {
+			if u.ChainID == v.Cfg.ChainID {
+				v.balances[string(u.Asset)+string(u.Address)] = u.Balance
+				v.stateTree.Put(u.StateKey(), u.Balance.Bytes())
+			}
+		}
+	}
+	if stateRoot, _ := v.stateTree.Root(); !bytes.Equal(block.StateRoot, stateRoot) {
+		return errIncorrectStateRoot
+	}
+	v.chainTree.PutVar(block.Num, block.Hash())
+	if chainRoot, _ := v.chainTree.Root(); !bytes.Equal(block.ChainRoot, chainRoot) {
+		return errIncorrectChainRoot
+	}
+
+	// verify totals statistic
+	v.stat.addBlock(block)
+	key := goldb.Key(dbTabStat, block.Timestamp, block.Num)
+	var st *Statistic
+	if ok, _ := v.db.GetVar(key, &st); !ok {
+		v.report.addIndexError(IndexStat, key, errIdxRecordNotFound)
+	} else if !bytes.Equal(st.Encode(), v.stat.Encode()) {
+		v.report.addIndexError(IndexStat, key, errIdxInvalidValue)
+	}
+
+	v.lastHeader = block.BlockHeader
+	return nil
+}
+
+// verifyTxIndexes verifies that all index records of the transaction exist
+func (v *chainVerifier) verifyTxIndexes(tx *chain.Transaction, txUID uint64) {
+	checkID := func(id IndexID, key []byte) {
+		if val, err := v.db.GetID(key); err != nil || val == 0 {
+			v.report.addIndexError(id, key, errIdxRecordNotFound)
+		} else if val != txUID {
+			v.report.addIndexError(id, key, errIdxInvalidValue)
+		}
+	}
+	checkBalance := func(id IndexID, key []byte, balance bignum.Int) {
+		var val bignum.Int
+		if ok, err := v.db.GetVar(key, &val); err != nil || !ok {
+			v.report.addIndexError(id, key, errIdxRecordNotFound)
+		} else if !val.Equal(balance) {
+			v.report.addIndexError(id, key, errIdxInvalidValue)
+		}
+	}
+
+	checkID(IndexTxID, goldb.Key(dbIdxTxID, tx.ID()))
+
+	if tx.Type == model.TxUser {
+		usr := tx.TxObject().(*txobj.User)
+		checkID(IndexUserID, goldb.Key(dbIdxUserID, usr.UserID()))
+		checkID(IndexUserNick, goldb.Key(dbIdxUserNick, usr.Nick))
+		if usr.ReferrerID != 0 {
+			checkID(IndexInvites, goldb.Key(dbIdxInvites, usr.ReferrerID, txUID))
+		}
+	}
+	for stIdx, u := range tx.StateUpdates {
+		if u.ChainID != v.Cfg.ChainID {
+			continue
+		}
+		checkBalance(IndexAssetAddr, goldb.Key(dbIdxAssetAddr, u.Asset, u.Address, txUID, stIdx), u.Balance)
+		if u.Memo != 0 {
+			checkBalance(IndexAssetAddrMemo, goldb.Key(dbIdxAssetAddrMemo, u.Asset, u.Address, u.Memo, txUID, stIdx), u.Balance)
+		}
+		key := goldb.Key(dbIdxTypeAssetAddr, tx.Type, u.Asset, u.Address, txUID)
+		if data, err := v.db.Get(key); err != nil || data == nil {
+			v.report.addIndexError(IndexTypeAssetAddr, key, errIdxRecordNotFound)
+		}
+	}
+}
+
+// verifyIndexRefs verifies that all index records refer to valid primary data
+func (v *chainVerifier) verifyIndexRefs(lastNum uint64) error {
+	maxTxUID := encodeTxUID(lastNum+1, 0)
+
+	txAt := func(txUID uint64) *chain.Transaction {
+		if txUID >= maxTxUID {
+			return nil
+		}
+		num, idx := decodeTxUID(txUID)
+		var tx *chain.Transaction
+		if ok, _ := v.db.GetVar(goldb.Key(dbTabTxs, num, idx), &tx); !ok {
+			return nil
+		}
+		return tx
+	}
+	hasUpdate := func(tx *chain.Transaction, stIdx int, asset, addr []byte, memo uint64) bool {
+		for i, u := range tx.StateUpdates {
+			if (stIdx < 0 || i == stIdx) && bytes.Equal(u.Asset, asset) && bytes.Equal(u.Address, addr) && (memo == 0 || u.Memo == memo) {
+				return true
+			}
+		}
+		return false
+	}
+	userTx := func(txUID uint64) *txobj.User {
+		if tx := txAt(txUID); tx != nil && tx.Type == model.TxUser {
+			return tx.TxObject().(*txobj.User)
+		}
+		return nil
+	}
+
+	checks := map[IndexID]indexCheck{
+		IndexTxID: func(rec goldb.Record) (bool, bool) {
+			var txID, txUID uint64
+			rec.MustDecodeKey(&txID)
+			rec.MustDecode(&txUID)
+			tx := txAt(txUID)
+			return txUID >= maxTxUID, tx != nil && tx.ID() == txID
+		},
+		IndexAssetAddr: func(rec goldb.Record) (bool, bool) {
+			var asset, addr []byte
+			var txUID uint64
+			var stIdx int
+			rec.MustDecodeKey(&asset, &addr, &txUID, &stIdx)
+			tx := txAt(txUID)
+			return txUID >= maxTxUID, tx != nil && hasUpdate(tx, stIdx, asset, addr, 0)
+		},
+		IndexAssetAddrMemo: func(rec goldb.Record) (bool, bool) {
+			var asset, addr []byte
+			var memo, txUID uint64
+			var stIdx int
+			rec.MustDecodeKey(&asset, &addr, &memo, &txUID, &stIdx)
+			tx := txAt(txUID)
+			return txUID >= maxTxUID, tx != nil && hasUpdate(tx, stIdx, asset, addr, memo)
+		},
+		IndexTypeAssetAddr: func(rec goldb.Record) (bool, bool) {
+			var txType int
+			var asset, addr []byte
+			var txUID uint64
+			rec.MustDecodeKey(&txType, &asset, &addr, &txUID)
+			tx := txAt(txUID)
+			return txUID >= maxTxUID, tx != nil && tx.Type == txType && hasUpdate(tx, -1, asset, addr, 0)
+		},
+		IndexUserID: func(rec goldb.Record) (bool, bool) {
+			var userID, txUID uint64
+			rec.MustDecodeKey(&userID)
+			rec.MustDecode(&txUID)
+			usr := userTx(txUID)
+			return txUID >= maxTxUID, usr != nil && usr.UserID() == userID
+		},
+		IndexUserNick: func(rec goldb.Record) (bool, bool) {
+			var nick string
+			var txUID uint64
+			rec.MustDecodeKey(&nick)
+			rec.MustDecode(&txUID)
+			usr := userTx(txUID)
+			return txUID >= maxTxUID, usr != nil && usr.Nick == nick
+		},
+		IndexInvites: func(rec goldb.Record) (bool, bool) {
+			var referrerID, txUID uint64
+			rec.MustDecodeKey(&referrerID, &txUID)
+			usr := userTx(txUID)
+			return txUID >= maxTxUID, usr != nil && usr.ReferrerID == referrerID
+		},
+		IndexStat: func(rec goldb.Record) (bool, bool) {
+			var ts int64
+			var num uint64
+			rec.MustDecodeKey(&ts, &num)
+			h := new(chain.BlockHeader)
+			ok, _ := v.db.GetVar(goldb.Key(dbTabHeaders, num), h)
+			return num > lastNum, ok && h.Timestamp == ts
+		},
+	}
+	for _, id := range AllIndexes {
+		fn := checks[id]
+		err := v.db.Fetch(goldb.NewQuery(goldb.Entity(id)), func(rec goldb.Record) error {
+			if skip, ok := safeCheck(fn, rec); !skip && !ok {
+				v.report.addIndexError(id, rec.Key, errIdxInvalidRef)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type indexCheck func(rec goldb.Record) (skip, ok bool)
+
+func safeCheck(fn indexCheck, rec goldb.Record) (skip, ok bool) {
+	defer func() {
+		if r := recover(); r != nil { // invalid record data
+			skip, ok = false, false
+		}
+	}()
+	return fn(rec)
+}
@@ -0,0 +1,64 @@
+package bcstore
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/goldb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainStorage_VerifyChain(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+
+	r, err := bc.VerifyChain()
+
+	assert.NoError(t, err)
+	assert.True(t, r.OK())
+	assert.EqualValues(t, 3, r.Blocks)
+	assert.EqualValues(t, 5, r.Txs)
+	assert.Empty(t, r.IndexErrors)
+}
+
+func TestChainStorage_VerifyChain_invalidBlock(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+
+	// corrupt transaction of block#2
+	tx, _ := bc.GetTransaction(2, 1)
+	tx.StateUpdates[0].Balance = coins(100500)
+	bc.db.PutVar(goldb.Key(dbTabTxs, 2, 1), tx)
+
+	r, err := bc.VerifyChain()
+
+	assert.NoError(t, err)
+	assert.False(t, r.OK())
+	assert.EqualValues(t, 1, r.Blocks)
+	assert.EqualValues(t, 2, r.InvalidBlock)
+	assert.Equal(t, chain.ErrInvalidTxsMerkleRoot.Error(), r.BlockError)
+	assert.Empty(t, r.IndexErrors)
+}
+
+func TestChainStorage_VerifyChain_indexErrors(t *testing.T) {
+	bc := newTestChainWithUsers(t)
+	defer bc.Drop()
+
+	bc.db.Delete(goldb.Key(dbIdxUserNick, "cat"))                                                    // lost record
+	bc.db.PutVar(goldb.Key(dbIdxAssetAddr, assets.MDC, cat, encodeTxUID(3, 1), 1), bignum.NewInt(1)) // invalid value
+	bc.db.PutID(goldb.Key(dbIdxTxID, 123), encodeTxUID(2, 0))                                        // invalid reference
+
+	r, err := bc.VerifyChain()
+
+	assert.NoError(t, err)
+	assert.False(t, r.OK())
+	assert.EqualValues(t, 3, r.Blocks)
+	assert.EqualValues(t, 0, r.InvalidBlock)
+	assert.Equal(t, []*IndexError{
+		{IndexUserNick, goldb.Key(dbIdxUserNick, "cat"), errIdxRecordNotFound.Error()},
+		{IndexAssetAddr, goldb.Key(dbIdxAssetAddr, assets.MDC, cat, encodeTxUID(3, 1), 1), errIdxInvalidValue.Error()},
+		{IndexTxID, goldb.Key(dbIdxTxID, 123), errIdxInvalidRef.Error()},
+	}, r.IndexErrors)
+}
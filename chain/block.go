@@ -34,6 +34,9 @@ func GenerateNewBlockEx(
 	nonce uint64,
 ) (block *Block, err error) {
 
+	pre := bc.LastBlockHeader()
+	miner := prv.PublicKey()
+
 	txCtx := NewSubContext(bc)
 	validTxs := txs[:0]
 	for _, tx := range txs {
@@ -44,7 +47,8 @@ func GenerateNewBlockEx(
 		} else if _tx != nil {
 			continue // skip. tx has registered
 		}
-		tx.bc = txCtx // set context
+		tx.SetBlockInfo(txCtx, pre.Num+1, len(validTxs), timestamp) // set context
+		tx.SetBlockMiner(miner)
 		if upd, err := tx.Execute(); err == nil {
 			tx.StateUpdates = upd
 			txCtx.State().Apply(upd)
@@ -55,8 +59,6 @@ func GenerateNewBlockEx(
 		return nil, nil
 	}
 
-	pre := bc.LastBlockHeader()
-
 	block = &Block{&BlockHeader{
 		Version:   0,
 		Network:   pre.Network,
@@ -65,7 +67,7 @@ func GenerateNewBlockEx(
 		PrevHash:  pre.Hash(),
 		Timestamp: timestamp,
 		Nonce:     nonce,
-		Miner:     prv.PublicKey(),
+		Miner:     miner,
 	}, validTxs}
 
 	stTree := bc.StateTree()
@@ -140,6 +142,11 @@ func (b *Block) verifyTxs() error {
 			return ErrTxInvalidNetworkID
 		}
 	}
+	return b.VerifyTxRoot()
+}
+
+// VerifyTxRoot verifies that block transactions match merkle root of the block header
+func (b *Block) VerifyTxRoot() error {
 	if txRoot := b.txRoot(); !bytes.Equal(b.TxRoot, txRoot) {
 		return ErrInvalidTxsMerkleRoot
 	}
@@ -12,6 +12,7 @@ var (
 	ErrInvalidGenesisBlock  = errors.New("block.Verify-error: invalid genesis block")
 	ErrEmptyMinerKey        = errors.New("block.Verify-error: empty miner public key")
 	ErrInvalidMinerKey      = errors.New("block.Verify-error: invalid miner public key")
+	ErrNotScheduledMiner    = errors.New("block.Verify-error: miner is not scheduled validator")
 	ErrInvalidBlockSig      = errors.New("block.Verify-error: invalid block signature")
 	ErrInvalidBlockNum      = errors.New("block.Verify-error: invalid block num")
 	ErrInvalidBlockTs       = errors.New("block.Verify-error: invalid block timestamp")
@@ -20,6 +21,7 @@ var (
 	ErrInvalidPrevHash      = errors.New("block.Verify-error: invalid previous block hash")
 	ErrInvalidTxSig         = errors.New("block.Verify-error: invalid tx signature")
 	ErrInvalidTxsMerkleRoot = errors.New("block.Verify-error: invalid txs merkle root")
+	ErrCheckpointMismatch   = errors.New("block.Verify-error: block does not match checkpoint")
 )
 
 var (
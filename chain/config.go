@@ -1,12 +1,25 @@
 package chain
 
-import "github.com/mediacoin-pro/core/crypto"
+import (
+	"runtime"
+
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/crypto"
+)
 
 type Config struct {
-	NetworkID      int
-	ChainID        uint64
-	MasterKey      string
-	VerifyTxsLevel int
+	NetworkID        int
+	ChainID          uint64
+	MasterKey        string
+	VerifyTxsLevel   int
+	VerifyTxsWorkers int          // count of workers for parallel verification of blocks (runtime.NumCPU() by default)
+	PoAHeight        uint64       // activation height of PoA-consensus by validators set (0 - blocks are signed by master key only)
+	Checkpoints      []Checkpoint // trusted hashes of blocks (see: VerifyTxLevel1)
+	FeeHeight        uint64       // activation height of min fee of transfers (0 - fee is not required)
+	FeePerByte       int64        // min fee (in nano-MDC) per byte of tx-data
+	FeePerOutput     int64        // min fee (in nano-MDC) per output of transfer
+	BurnFees         bool         // fees are burned (by default fees are credited to miner of the block)
+	SeqHeight        uint64       // activation height of sequence numbers of sender transactions (0 - off; see: TxVersionSeq)
 
 	_mkey *crypto.PublicKey
 }
@@ -30,8 +43,67 @@ func (c *Config) MasterPubKey() *crypto.PublicKey {
 	return c._mkey
 }
 
+// IsPoA returns true if the block must be signed by scheduled validator of PoA-consensus
+func (c *Config) IsPoA(blockNum uint64) bool {
+	return c.PoAHeight > 0 && blockNum >= c.PoAHeight
+}
+
+// IsSeq returns true if sequenced transactions are allowed in the block
+func (c *Config) IsSeq(blockNum uint64) bool {
+	return c.SeqHeight > 0 && blockNum >= c.SeqHeight
+}
+
+// MinTxFee returns min fee of transaction in the block (0 - fee is not required)
+func (c *Config) MinTxFee(blockNum uint64, dataSize, outputs int) bignum.Int {
+	if c.FeeHeight == 0 || blockNum < c.FeeHeight {
+		return bignum.Int{}
+	}
+	return bignum.NewInt(c.FeePerByte*int64(dataSize) + c.FeePerOutput*int64(outputs))
+}
+
+// VerifyWorkers returns count of workers for parallel verification of blocks
+func (c *Config) VerifyWorkers() int {
+	if c.VerifyTxsWorkers > 0 {
+		return c.VerifyTxsWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// IsCheckpointed returns true if transactions of the block can be trusted by checkpoints.
+// Transactions are not verified only when the block is linked to checkpoint hash by headers.
+func (c *Config) IsCheckpointed(blockNum uint64) bool {
+	return c.VerifyTxsLevel < VerifyTxLevel2 && blockNum <= c.LastCheckpoint()
+}
+
+// LastCheckpoint returns num of the last checkpoint (0 - no checkpoints)
+func (c *Config) LastCheckpoint() (num uint64) {
+	for _, cp := range c.Checkpoints {
+		if cp.Num > num {
+			num = cp.Num
+		}
+	}
+	return
+}
+
+// CheckpointHash returns trusted hash of the block (nil - no checkpoint)
+func (c *Config) CheckpointHash(blockNum uint64) []byte {
+	for _, cp := range c.Checkpoints {
+		if cp.Num == blockNum {
+			return cp.Hash
+		}
+	}
+	return nil
+}
+
+// Checkpoint is trusted hash of block
+type Checkpoint struct {
+	Num  uint64
+	Hash []byte
+}
+
 const (
-	VerifyTxLevel1 = 1
+	VerifyTxLevel1 = 1 // verify transactions of blocks after the last checkpoint (other blocks are verified by headers and roots)
+	VerifyTxLevel2 = 2 // verify transactions of all blocks
 )
 
 func GenesisBlockHeader(cfg *Config) *BlockHeader {
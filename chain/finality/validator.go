@@ -0,0 +1,181 @@
+package finality
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/crypto"
+)
+
+// Chain is blockchain storage of finality certificates (see bcstore.ChainStorage)
+type Chain interface {
+	Config() *chain.Config
+	LastBlockHeader() *chain.BlockHeader
+	BlockHeader(num uint64) (*chain.BlockHeader, error)
+	ValidatorsAt(num uint64) ([]*crypto.PublicKey, error) // validators set that finalizes the block
+	FinalizedNum() uint64
+	PutCertificate(cert *Certificate) error
+}
+
+// Network delivers votes to all validators
+type Network interface {
+	Broadcast(v *Vote)
+}
+
+// Validator is finality gadget of the validator.
+// For each not finalized block of the local chain the validator sends prevote;
+// after prevotes of quorum (more than 2/3) of validators it sends precommit;
+// precommits of quorum of validators make the certificate of finality of the block.
+// The validator votes only once for each block height and vote type.
+type Validator struct {
+	bc  Chain
+	key *crypto.PrivateKey
+	net Network
+
+	mx    sync.Mutex
+	votes map[voteKey]map[string]*Vote // (type, num, blockHash) => validator => vote
+	voted map[voteKey]bool             // (type, num) => vote has been sent
+}
+
+type voteKey struct {
+	typ  int
+	num  uint64
+	hash string
+}
+
+func NewValidator(bc Chain, key *crypto.PrivateKey, net Network) *Validator {
+	return &Validator{
+		bc:    bc,
+		key:   key,
+		net:   net,
+		votes: map[voteKey]map[string]*Vote{},
+		voted: map[voteKey]bool{},
+	}
+}
+
+// OnNewBlock sends prevotes for not finalized blocks of the local chain
+func (v *Validator) OnNewBlock() error {
+	last := v.bc.LastBlockHeader().Num
+	for num := v.bc.FinalizedNum() + 1; num <= last; num++ {
+		h, err := v.bc.BlockHeader(num)
+		if err != nil {
+			return err
+		}
+		v.vote(Prevote, h)
+		if err = v.checkQuorum(h); err != nil { // votes can be received before the block
+			return err
+		}
+	}
+	v.prune()
+	return nil
+}
+
+// OnVote handles the vote of validator
+func (v *Validator) OnVote(vote *Vote) error {
+	if err := vote.Verify(); err != nil {
+		return err
+	}
+	if vote.ChainID != v.bc.Config().ChainID {
+		return chain.ErrInvalidChainID
+	}
+	if vote.Num <= v.bc.FinalizedNum() {
+		return nil // block has been finalized
+	}
+	validators, err := v.bc.ValidatorsAt(vote.Num)
+	if err != nil {
+		return err
+	}
+	if !containsKey(validators, vote.Validator) {
+		return ErrNotValidator
+	}
+
+	v.mx.Lock()
+	key := voteKey{vote.Type, vote.Num, string(vote.BlockHash)}
+	if v.votes[key] == nil {
+		v.votes[key] = map[string]*Vote{}
+	}
+	v.votes[key][string(vote.Validator.Encode())] = vote
+	v.mx.Unlock()
+
+	if vote.Num > v.bc.LastBlockHeader().Num {
+		return nil // unknown block
+	}
+	h, err := v.bc.BlockHeader(vote.Num)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Hash(), vote.BlockHash) {
+		return nil // vote for another block
+	}
+	return v.checkQuorum(h)
+}
+
+// checkQuorum sends precommit or puts certificate of finality on quorum of votes for the block
+func (v *Validator) checkQuorum(h *chain.BlockHeader) error {
+	if h.Num <= v.bc.FinalizedNum() {
+		return nil
+	}
+	validators, err := v.bc.ValidatorsAt(h.Num)
+	if err != nil {
+		return err
+	}
+	quorum := chain.ValidatorsQuorum(len(validators))
+	hash := h.Hash()
+
+	if v.countVotes(Prevote, h.Num, hash) >= quorum {
+		v.vote(Precommit, h)
+	}
+	if precommits := v.getVotes(Precommit, h.Num, hash); len(precommits) >= quorum {
+		return v.bc.PutCertificate(&Certificate{
+			Num:        h.Num,
+			BlockHash:  hash,
+			Precommits: precommits,
+		})
+	}
+	return nil
+}
+
+func (v *Validator) vote(typ int, h *chain.BlockHeader) {
+	v.mx.Lock()
+	key := voteKey{typ: typ, num: h.Num}
+	voted := v.voted[key]
+	v.voted[key] = true
+	v.mx.Unlock()
+
+	if !voted {
+		v.net.Broadcast(NewVote(typ, h, v.key))
+	}
+}
+
+func (v *Validator) countVotes(typ int, num uint64, hash []byte) int {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	return len(v.votes[voteKey{typ, num, string(hash)}])
+}
+
+func (v *Validator) getVotes(typ int, num uint64, hash []byte) (votes []*Vote) {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	for _, vote := range v.votes[voteKey{typ, num, string(hash)}] {
+		votes = append(votes, vote)
+	}
+	return
+}
+
+// prune removes votes of finalized blocks
+func (v *Validator) prune() {
+	finalized := v.bc.FinalizedNum()
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	for key := range v.votes {
+		if key.num <= finalized {
+			delete(v.votes, key)
+		}
+	}
+	for key := range v.voted {
+		if key.num <= finalized {
+			delete(v.voted, key)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package finality
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/crypto"
+)
+
+// Types of votes
+const (
+	Prevote   = 1
+	Precommit = 2
+)
+
+var (
+	ErrInvalidVoteType = errors.New("finality: invalid vote type")
+	ErrInvalidVoteSig  = errors.New("finality: invalid vote signature")
+	ErrNotValidator    = errors.New("finality: vote of not validator")
+	ErrInvalidCert     = errors.New("finality: invalid certificate")
+	ErrNoQuorum        = errors.New("finality: not enough precommits of validators")
+)
+
+// Vote is signed message of validator for the block
+type Vote struct {
+	Type      int               // Prevote or Precommit
+	ChainID   uint64            //
+	Num       uint64            // block num
+	BlockHash bin.Bytes         // block hash := BlockHeader.Hash()
+	Validator *crypto.PublicKey // validator public key
+	Sig       bin.Bytes         // validator signature := validatorKey.Sign( vote.Hash() )
+}
+
+// NewVote returns vote for the block signed by the validator key
+func NewVote(typ int, h *chain.BlockHeader, key *crypto.PrivateKey) *Vote {
+	v := &Vote{
+		Type:      typ,
+		ChainID:   h.ChainID,
+		Num:       h.Num,
+		BlockHash: h.Hash(),
+		Validator: key.PublicKey(),
+	}
+	v.Sig = key.Sign(v.Hash())
+	return v
+}
+
+func (v *Vote) String() string {
+	return fmt.Sprintf("[VOTE-%d block:%d 0x%x validator:%x]", v.Type, v.Num, v.BlockHash[:8], v.Validator.ID())
+}
+
+// Hash returns hash of signed vote data
+func (v *Vote) Hash() []byte {
+	return bin.Hash256(v.Type, v.ChainID, v.Num, v.BlockHash)
+}
+
+// Verify verifies vote type and validator signature
+func (v *Vote) Verify() error {
+	if v.Type != Prevote && v.Type != Precommit {
+		return ErrInvalidVoteType
+	}
+	if v.Validator.Empty() || !v.Validator.Verify(v.Hash(), v.Sig) {
+		return ErrInvalidVoteSig
+	}
+	return nil
+}
+
+func (v *Vote) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		v.Type,
+		v.ChainID,
+		v.Num,
+		v.BlockHash,
+		v.Validator,
+		v.Sig,
+	)
+}
+
+func (v *Vote) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&v.Type,
+		&v.ChainID,
+		&v.Num,
+		&v.BlockHash,
+		&v.Validator,
+		&v.Sig,
+	)
+}
+
+// Certificate is proof of finality of the block (precommits of quorum of validators)
+type Certificate struct {
+	Num        uint64    // block num
+	BlockHash  bin.Bytes // block hash
+	Precommits []*Vote   // precommits of validators
+}
+
+func (c *Certificate) String() string {
+	return fmt.Sprintf("[CERT block:%d 0x%x precommits:%d]", c.Num, c.BlockHash[:8], len(c.Precommits))
+}
+
+func (c *Certificate) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		c.Num,
+		c.BlockHash,
+		c.Precommits,
+	)
+}
+
+func (c *Certificate) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&c.Num,
+		&c.BlockHash,
+		&c.Precommits,
+	)
+}
+
+// Verify verifies that the certificate contains valid precommits of quorum (more than 2/3) of validators
+func (c *Certificate) Verify(chainID uint64, validators []*crypto.PublicKey) error {
+	signed := map[string]bool{}
+	for _, v := range c.Precommits {
+		if v.Type != Precommit || v.ChainID != chainID || v.Num != c.Num || !bytes.Equal(v.BlockHash, c.BlockHash) {
+			return ErrInvalidCert
+		}
+		if err := v.Verify(); err != nil {
+			return err
+		}
+		if !containsKey(validators, v.Validator) {
+			return ErrNotValidator
+		}
+		signed[string(v.Validator.Encode())] = true
+	}
+	if len(signed) < chain.ValidatorsQuorum(len(validators)) {
+		return ErrNoQuorum
+	}
+	return nil
+}
+
+func containsKey(keys []*crypto.PublicKey, pub *crypto.PublicKey) bool {
+	for _, k := range keys {
+		if k.Equal(pub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package finality
+
+import (
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	key1 = crypto.NewPrivateKey()
+	key2 = crypto.NewPrivateKey()
+	key3 = crypto.NewPrivateKey()
+	key4 = crypto.NewPrivateKey()
+
+	testValidators = []*crypto.PublicKey{key1.PublicKey(), key2.PublicKey(), key3.PublicKey(), key4.PublicKey()}
+)
+
+func newTestHeader() *chain.BlockHeader {
+	return &chain.BlockHeader{
+		Version: 1,
+		ChainID: 1,
+		Num:     10,
+		Nonce:   123,
+	}
+}
+
+func TestVote_Encode(t *testing.T) {
+	v := NewVote(Precommit, newTestHeader(), key1)
+
+	var v1 Vote
+	err := v1.Decode(v.Encode())
+
+	assert.NoError(t, err)
+	assert.Equal(t, v.Encode(), v1.Encode())
+	assert.NoError(t, v1.Verify())
+}
+
+func TestVote_Verify(t *testing.T) {
+	v0 := NewVote(Prevote, newTestHeader(), key1)
+	v1 := NewVote(Prevote, newTestHeader(), key1)
+	v1.Num++
+	v2 := NewVote(3, newTestHeader(), key1)
+
+	assert.NoError(t, v0.Verify())
+	assert.Equal(t, ErrInvalidVoteSig, v1.Verify())
+	assert.Equal(t, ErrInvalidVoteType, v2.Verify())
+}
+
+func TestCertificate_Verify(t *testing.T) {
+	h := newTestHeader()
+	newCert := func(keys ...*crypto.PrivateKey) *Certificate {
+		c := &Certificate{Num: h.Num, BlockHash: h.Hash()}
+		for _, key := range keys {
+			c.Precommits = append(c.Precommits, NewVote(Precommit, h, key))
+		}
+		return c
+	}
+	prevote := newCert(key1, key2)
+	prevote.Precommits = append(prevote.Precommits, NewVote(Prevote, h, key3))
+
+	var decoded Certificate
+	err := decoded.Decode(newCert(key1, key2, key3).Encode())
+
+	assert.NoError(t, err)
+	assert.NoError(t, decoded.Verify(1, testValidators))
+	assert.NoError(t, newCert(key1, key2, key3, key4).Verify(1, testValidators))
+	assert.Equal(t, ErrNoQuorum, newCert(key1, key2).Verify(1, testValidators))
+	assert.Equal(t, ErrNoQuorum, newCert(key1, key2, key2).Verify(1, testValidators))
+	assert.Equal(t, ErrNotValidator, newCert(key1, key2, crypto.NewPrivateKey()).Verify(1, testValidators))
+	assert.Equal(t, ErrInvalidCert, newCert(key1, key2, key3).Verify(2, testValidators))
+	assert.Equal(t, ErrInvalidCert, prevote.Verify(1, testValidators))
+}
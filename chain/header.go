@@ -132,10 +132,13 @@ func (b *BlockHeader) VerifyHeader(pre *BlockHeader, cfg *Config) error {
 			return ErrInvalidPrevHash
 		}
 	}
+	if cp := cfg.CheckpointHash(b.Num); cp != nil && !bytes.Equal(blockHash, cp) {
+		return ErrCheckpointMismatch
+	}
 	if b.Miner.Empty() {
 		return ErrEmptyMinerKey
 	}
-	if !b.Miner.Equal(cfg.MasterPubKey()) {
+	if !cfg.IsPoA(b.Num) && !b.Miner.Equal(cfg.MasterPubKey()) { // PoA-miner is verified by the state (see VerifyMiner)
 		return ErrInvalidMinerKey
 	}
 	if !b.Miner.Verify(b.sigHash(), b.Sig) {
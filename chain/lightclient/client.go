@@ -0,0 +1,181 @@
+package lightclient
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/bin"
+)
+
+const (
+	headersBatchSize   = 1000
+	verifiedHashesSize = 10000 // count of the last verified headers that are accepted in proofs
+)
+
+var (
+	ErrUnknownHeader = errors.New("lightclient: proof header does not match synced chain")
+	ErrPoAHeader     = errors.New("lightclient: PoA-headers are not supported")
+	errInvalidTx     = errors.New("lightclient: node returned another transaction")
+)
+
+// Transport sends encoded request to full node and returns encoded response (see: bcstore.ChainStorage.ServeLight)
+type Transport interface {
+	Call(req []byte) ([]byte, error)
+}
+
+// TransportFunc is in-memory transport
+type TransportFunc func(req []byte) ([]byte, error)
+
+func (fn TransportFunc) Call(req []byte) ([]byte, error) {
+	return fn(req)
+}
+
+// Client is light client that syncs and verifies block headers only.
+// Balances and transactions are requested from full node with proofs and verified by the last synced header.
+// Client keeps the last verified header and hashes of the last verified headers.
+//
+// Miners of PoA-blocks can not be verified without validators set, so headers since cfg.PoAHeight are refused.
+type Client struct {
+	cfg *chain.Config
+	tr  Transport
+
+	mx     sync.Mutex
+	head   *chain.BlockHeader
+	hashes map[uint64][]byte // blockNum => hash of verified header
+}
+
+func NewClient(cfg *chain.Config, tr Transport) *Client {
+	return &Client{
+		cfg:    cfg,
+		tr:     tr,
+		head:   chain.GenesisBlockHeader(cfg),
+		hashes: map[uint64][]byte{},
+	}
+}
+
+// Head returns the last verified block header
+func (c *Client) Head() *chain.BlockHeader {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.head
+}
+
+// Sync downloads and verifies new block headers
+func (c *Client) Sync() error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.syncHeaders(0)
+}
+
+// syncHeaders syncs headers up to block num (0 - up to the last block of node)
+func (c *Client) syncHeaders(num uint64) error {
+	for num == 0 || c.head.Num < num {
+		limit := headersBatchSize
+		if num != 0 && num-c.head.Num < uint64(limit) {
+			limit = int(num - c.head.Num)
+		}
+		data, err := c.call(&bcstore.LightRequest{Method: bcstore.LightGetHeaders, Offset: c.head.Num, Limit: limit})
+		if err != nil {
+			return err
+		}
+		var headers []*chain.BlockHeader
+		if err = bin.Decode(data, &headers); err != nil {
+			return err
+		}
+		for _, h := range headers {
+			if c.cfg.IsPoA(h.Num) {
+				return ErrPoAHeader
+			}
+			if err = h.VerifyHeader(c.head, c.cfg); err != nil {
+				return err
+			}
+			c.setHead(h)
+		}
+		if len(headers) < limit {
+			break
+		}
+	}
+	return nil
+}
+
+func (c *Client) setHead(h *chain.BlockHeader) {
+	c.head = h
+	c.hashes[h.Num] = h.Hash()
+	if h.Num >= verifiedHashesSize {
+		delete(c.hashes, h.Num-verifiedHashesSize)
+	}
+}
+
+// verifyHeader syncs headers up to the header of proof and checks that the header is in synced chain
+func (c *Client) verifyHeader(h *chain.BlockHeader) error {
+	if h == nil {
+		return bcstore.ErrInvalidProof
+	}
+	if h.Num > c.head.Num {
+		if err := c.syncHeaders(h.Num); err != nil {
+			return err
+		}
+	}
+	if hash, ok := c.hashes[h.Num]; !ok || !bytes.Equal(hash, h.Hash()) {
+		return ErrUnknownHeader
+	}
+	return nil
+}
+
+// Balance returns verified balance of the address by the last block of node
+func (c *Client) Balance(addr, asset []byte) (bignum.Int, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	data, err := c.call(&bcstore.LightRequest{Method: bcstore.LightGetBalance, Asset: asset, Address: addr})
+	if err != nil {
+		return bignum.Int{}, err
+	}
+	var p bcstore.BalanceProof
+	if err = p.Decode(data); err != nil {
+		return bignum.Int{}, err
+	}
+	if !bytes.Equal(p.Asset, asset) || !bytes.Equal(p.Address, addr) {
+		return bignum.Int{}, bcstore.ErrInvalidProof
+	}
+	if err = p.Verify(); err != nil {
+		return bignum.Int{}, err
+	}
+	if err = c.verifyHeader(p.Header); err != nil {
+		return bignum.Int{}, err
+	}
+	return bignum.NewFromBytes(p.Value), nil
+}
+
+// Transaction returns verified transaction by txID
+func (c *Client) Transaction(txID uint64) (*chain.Transaction, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	data, err := c.call(&bcstore.LightRequest{Method: bcstore.LightGetTx, TxID: txID})
+	if err != nil {
+		return nil, err
+	}
+	var p bcstore.TxProof
+	if err = p.Decode(data); err != nil {
+		return nil, err
+	}
+	if p.Tx == nil || p.Tx.ID() != txID {
+		return nil, errInvalidTx
+	}
+	if err = p.Verify(); err != nil {
+		return nil, err
+	}
+	if err = c.verifyHeader(p.BlockInclusion.Head); err != nil {
+		return nil, err
+	}
+	return p.Tx, nil
+}
+
+func (c *Client) call(req *bcstore.LightRequest) ([]byte, error) {
+	return c.tr.Call(req.Encode())
+}
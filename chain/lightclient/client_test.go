@@ -0,0 +1,170 @@
+package lightclient
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	masterKey = crypto.NewPrivateKey()
+	aliceKey  = crypto.NewPrivateKey()
+	bobKey    = crypto.NewPrivateKey()
+
+	alice = aliceKey.PublicKey().Address()
+	bob   = bobKey.PublicKey().Address()
+)
+
+func coins(n int64) bignum.Int {
+	return bignum.NewInt(n * assets.Coin)
+}
+
+func newTestConfig() *chain.Config {
+	cfg := chain.NewConfig()
+	cfg.MasterKey = masterKey.PublicKey().String()
+	return cfg
+}
+
+func newTransfer(bc chain.BCContext, amount int64) *chain.Transaction {
+	return txobj.NewSimpleTransfer(bc, aliceKey.PublicKey(), aliceKey, assets.MDC, coins(amount), 0, bob, 0, "", 0)
+}
+
+// newTestChain returns storage with n blocks
+func newTestChain(t *testing.T, n int) *bcstore.ChainStorage {
+	bc := bcstore.NewChainStorage(fmt.Sprintf("%s/test-lightclient-%x.db", os.TempDir(), rand.Int()), newTestConfig())
+	_, err := bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewEmission(bc, masterKey, assets.MDC, "", []*txobj.EmissionOutput{
+			{Address: alice, Amount: coins(1000)},
+		}),
+	}, masterKey)
+	assert.NoError(t, err)
+	for i := 1; i < n; i++ {
+		_, err = bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, 1), newTransfer(bc, 2)}, masterKey)
+		assert.NoError(t, err)
+	}
+	return bc
+}
+
+func TestClient_Sync(t *testing.T) {
+	bc := newTestChain(t, 10)
+	defer bc.Drop()
+	c := NewClient(newTestConfig(), TransportFunc(bc.ServeLight))
+
+	err := c.Sync()
+
+	assert.NoError(t, err)
+	assert.Equal(t, bc.LastBlock().Hash(), c.Head().Hash())
+}
+
+func TestClient_Balance(t *testing.T) {
+	bc := newTestChain(t, 10)
+	defer bc.Drop()
+	c := NewClient(newTestConfig(), TransportFunc(bc.ServeLight))
+	assert.NoError(t, c.Sync())
+
+	// new block after sync
+	_, err := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, 5)}, masterKey)
+	assert.NoError(t, err)
+
+	balance, err := c.Balance(bob, assets.MDC)
+
+	assert.NoError(t, err)
+	assert.Equal(t, coins(32).String(), balance.String())
+	assert.Equal(t, bc.LastBlock().Hash(), c.Head().Hash())
+}
+
+func TestClient_Transaction(t *testing.T) {
+	bc := newTestChain(t, 10)
+	defer bc.Drop()
+	c := NewClient(newTestConfig(), TransportFunc(bc.ServeLight))
+	block, _ := bc.GetBlock(4)
+	txID := block.Txs[1].ID()
+
+	tx, err := c.Transaction(txID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, txID, tx.ID())
+	assert.Equal(t, block.Txs[1].Encode(), tx.Encode())
+	assert.Equal(t, bc.LastBlock().Hash(), c.Head().Hash())
+}
+
+func TestClient_invalidProofs(t *testing.T) {
+	bc := newTestChain(t, 5)
+	defer bc.Drop()
+
+	// node changes balance value
+	c := NewClient(newTestConfig(), TransportFunc(func(req []byte) ([]byte, error) {
+		resp, err := bc.ServeLight(req)
+		var r bcstore.LightRequest
+		r.Decode(req)
+		if err == nil && r.Method == bcstore.LightGetBalance {
+			var p bcstore.BalanceProof
+			p.Decode(resp)
+			p.Value = coins(1e6).Bytes()
+			resp = p.Encode()
+		}
+		return resp, err
+	}))
+	_, err := c.Balance(bob, assets.MDC)
+	assert.Equal(t, bcstore.ErrInvalidProof, err)
+
+	// node returns header of another chain
+	other := newTestChain(t, 5)
+	defer other.Drop()
+	c = NewClient(newTestConfig(), TransportFunc(func(req []byte) ([]byte, error) {
+		var r bcstore.LightRequest
+		r.Decode(req)
+		if r.Method == bcstore.LightGetHeaders {
+			return bc.ServeLight(req)
+		}
+		return other.ServeLight(req)
+	}))
+	_, err = c.Balance(bob, assets.MDC)
+	assert.Equal(t, ErrUnknownHeader, err)
+}
+
+func TestClient_Balance_olderHeader(t *testing.T) {
+	bc := newTestChain(t, 10)
+	defer bc.Drop()
+	var staleProof []byte // balance proof of lagging node
+	c := NewClient(newTestConfig(), TransportFunc(func(req []byte) ([]byte, error) {
+		var r bcstore.LightRequest
+		r.Decode(req)
+		if r.Method == bcstore.LightGetBalance && staleProof != nil {
+			return staleProof, nil
+		}
+		return bc.ServeLight(req)
+	}))
+	staleProof, _ = bc.ServeLight((&bcstore.LightRequest{Method: bcstore.LightGetBalance, Asset: assets.MDC, Address: bob}).Encode())
+	_, err := bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, 5)}, masterKey)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Sync())
+
+	balance, err := c.Balance(bob, assets.MDC)
+
+	assert.NoError(t, err)
+	assert.Equal(t, coins(27).String(), balance.String())
+	assert.Equal(t, bc.LastBlock().Hash(), c.Head().Hash())
+}
+
+func TestClient_Sync_PoA(t *testing.T) {
+	bc := newTestChain(t, 10)
+	defer bc.Drop()
+	cfg := newTestConfig()
+	cfg.PoAHeight = 5
+	c := NewClient(cfg, TransportFunc(bc.ServeLight))
+
+	err := c.Sync()
+
+	assert.Equal(t, ErrPoAHeader, err)
+	assert.EqualValues(t, 4, c.Head().Num)
+}
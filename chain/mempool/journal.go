@@ -0,0 +1,240 @@
+package mempool
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/common/xlog"
+)
+
+const (
+	journalOpPut    = 1
+	journalOpRemove = 2
+
+	journalCompactMin = 1000 // min count of records for auto-compaction of the journal
+)
+
+// journal is append-only file of changes of the pool.
+// Each record is length-prefixed bin-encoded (op, txID, expires, tx-data)
+type journal struct {
+	filename string
+	f        *os.File
+	records  int // count of records in the file
+}
+
+type journalRecord struct {
+	op      int
+	txID    uint64
+	expires time.Time
+	data    []byte
+}
+
+func (r *journalRecord) Encode() []byte {
+	expires := r.expires
+	if expires.IsZero() { // unlimited TTL
+		expires = time.Unix(0, 0)
+	}
+	return bin.Encode(r.op, r.txID, expires, r.data)
+}
+
+func (r *journalRecord) Decode(data []byte) error {
+	if err := bin.Decode(data, &r.op, &r.txID, &r.expires, &r.data); err != nil {
+		return err
+	}
+	if r.expires.UnixNano() == 0 {
+		r.expires = time.Time{}
+	}
+	return nil
+}
+
+// readJournal returns list of records of the journal file; broken tail of the file is ignored
+func readJournal(filename string) (records []*journalRecord, err error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return
+	}
+	defer f.Close()
+	r := bin.NewReader(bufio.NewReader(f))
+	for {
+		data, err := r.ReadBytes()
+		if err != nil { // io.EOF or unfinished record
+			break
+		}
+		rec := new(journalRecord)
+		if rec.Decode(data) != nil {
+			break
+		}
+		records = append(records, rec)
+	}
+	return
+}
+
+// createJournal rewrites the journal file by the list of transactions
+func createJournal(filename string, items []*item) (j *journal, err error) {
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	w := bufio.NewWriter(f)
+	for _, it := range items {
+		if err = writeJournalRecord(w, putRecord(it)); err != nil {
+			f.Close()
+			return
+		}
+	}
+	if err = w.Flush(); err == nil {
+		err = f.Sync()
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return
+	}
+	if err = os.Rename(tmp, filename); err != nil {
+		return
+	}
+	if f, err = os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return
+	}
+	return &journal{filename: filename, f: f, records: len(items)}, nil
+}
+
+func putRecord(it *item) *journalRecord {
+	return &journalRecord{
+		op:      journalOpPut,
+		txID:    it.tx.ID(),
+		expires: it.expires,
+		data:    it.tx.Encode(),
+	}
+}
+
+func writeJournalRecord(w io.Writer, rec *journalRecord) error {
+	_, err := bin.Write(w, rec.Encode())
+	return err
+}
+
+func (j *journal) put(it *item) error {
+	return j.write(putRecord(it))
+}
+
+func (j *journal) remove(txID uint64) error {
+	return j.write(&journalRecord{op: journalOpRemove, txID: txID})
+}
+
+func (j *journal) write(rec *journalRecord) error {
+	j.records++
+	return writeJournalRecord(j.f, rec)
+}
+
+func (j *journal) close() error {
+	return j.f.Close()
+}
+
+// OpenJournal loads transactions from the journal file and starts journaling of changes of the pool.
+// Confirmed, expired and invalid transactions of the journal are dropped.
+func (s *Storage) OpenJournal(filename string) error {
+	if err := s.CloseJournal(); err != nil {
+		return err
+	}
+	records, err := readJournal(filename)
+	if err != nil {
+		return err
+	}
+	// replay the journal
+	type journalTx struct {
+		data    []byte
+		expires time.Time
+	}
+	var (
+		txs   = map[uint64]*journalTx{}
+		order []uint64
+	)
+	for _, rec := range records {
+		switch rec.op {
+		case journalOpPut:
+			if txs[rec.txID] == nil {
+				order = append(order, rec.txID)
+			}
+			txs[rec.txID] = &journalTx{rec.data, rec.expires}
+		case journalOpRemove:
+			delete(txs, rec.txID)
+		}
+	}
+	now := timeNow()
+	for _, txID := range order {
+		v := txs[txID]
+		if v == nil || isExpired(v.expires, now) {
+			continue
+		}
+		tx := new(chain.Transaction)
+		if tx.Decode(v.data) != nil {
+			continue
+		}
+		if s.put(tx) == nil { // verifies tx and drops confirmed txs
+			s.mx.Lock()
+			if it := s.txs[txID]; it != nil {
+				it.expires = v.expires
+			}
+			s.mx.Unlock()
+		}
+	}
+
+	// compact the journal
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.journal, err = createJournal(filename, s.items()); err == nil {
+		s.popped = map[uint64]*item{}
+	}
+	return err
+}
+
+// CloseJournal stops journaling of changes of the pool
+func (s *Storage) CloseJournal() (err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.journal != nil {
+		err = s.journal.close()
+		s.journal = nil
+		s.popped = nil
+	}
+	return
+}
+
+// CompactJournal rewrites the journal file by actual transactions of the pool
+func (s *Storage) CompactJournal() (err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.compactJournal()
+}
+
+// compactJournal rewrites the journal by transactions of the pool and popped transactions; the old journal is kept on error
+func (s *Storage) compactJournal() error {
+	if s.journal == nil {
+		return nil
+	}
+	j, err := createJournal(s.journal.filename, append(s.items(), sortedItems(s.popped)...))
+	if err != nil {
+		return err
+	}
+	if err = s.journal.close(); err != nil {
+		xlog.Error.Printf("mempool> journal.close-error: %v", err)
+	}
+	s.journal = j
+	return nil
+}
+
+// autoCompactJournal compacts the journal when most of its records are obsolete
+func (s *Storage) autoCompactJournal() error {
+	if j := s.journal; j != nil && j.records > journalCompactMin && j.records > 2*(len(s.txs)+len(s.popped)) {
+		return s.compactJournal()
+	}
+	return nil
+}
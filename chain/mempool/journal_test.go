@@ -0,0 +1,178 @@
+package mempool
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/stretchr/testify/assert"
+)
+
+func tempJournalFile() string {
+	return fmt.Sprintf("%s/test-mempool-%x.journal", os.TempDir(), rand.Int())
+}
+
+func TestStorage_OpenJournal(t *testing.T) {
+	filename := tempJournalFile()
+	defer os.Remove(filename)
+	bc := newTestBC()
+	a1, a2, a3, b1 := newTx(aliceKey, 10), newTx(aliceKey, 20), newTx(aliceKey, 30), newTx(bobKey, 15)
+
+	pool := NewStorage(bc, nil)
+	err := pool.OpenJournal(filename)
+	assert.NoError(t, err)
+	pool.Put(a1, a2, a3, b1)
+	pool.RemoveTx(a3.ID())
+	assert.Equal(t, a1, pool.Pop())
+	pool.RemoveTx(a1.ID()) // a1 has been confirmed
+	pool.CloseJournal()
+	bc.txs[b1.ID()] = b1 // b1 has been confirmed
+
+	pool = NewStorage(bc, nil)
+	err = pool.OpenJournal(filename)
+	defer pool.CloseJournal()
+
+	assert.NoError(t, err)
+	txs, _ := pool.AllTxs()
+	assert.Equal(t, []uint64{a2.ID()}, txIDs(txs))
+	assert.Equal(t, 1, pool.journal.records) // journal is compacted
+}
+
+func TestStorage_OpenJournal_brokenTail(t *testing.T) {
+	filename := tempJournalFile()
+	defer os.Remove(filename)
+	a1, a2 := newTx(aliceKey, 10), newTx(aliceKey, 20)
+	pool := NewStorage(newTestBC(), nil)
+	pool.OpenJournal(filename)
+	pool.Put(a1, a2)
+	pool.CloseJournal()
+	st, _ := os.Stat(filename)
+	os.Truncate(filename, st.Size()-10)
+
+	pool = NewStorage(newTestBC(), nil)
+	err := pool.OpenJournal(filename)
+	defer pool.CloseJournal()
+
+	assert.NoError(t, err)
+	txs, _ := pool.AllTxs()
+	assert.Equal(t, []uint64{a1.ID()}, txIDs(txs))
+}
+
+func TestStorage_OpenJournal_expired(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	filename := tempJournalFile()
+	defer os.Remove(filename)
+	a1, a2 := newTx(aliceKey, 10), newTx(aliceKey, 20)
+	pool := NewStorage(newTestBC(), &Config{TTL: time.Minute})
+	pool.OpenJournal(filename)
+	pool.Put(a1)
+	now = now.Add(30 * time.Second)
+	pool.Put(a2)
+	pool.CloseJournal()
+
+	now = now.Add(31 * time.Second)
+	pool = NewStorage(newTestBC(), &Config{TTL: time.Minute})
+	err := pool.OpenJournal(filename)
+	defer pool.CloseJournal()
+
+	assert.NoError(t, err)
+	txs, _ := pool.AllTxs()
+	assert.Equal(t, []uint64{a2.ID()}, txIDs(txs))
+}
+
+func TestStorage_OpenJournal_unlimitedTTL(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	filename := tempJournalFile()
+	defer os.Remove(filename)
+	a1 := newTx(aliceKey, 10)
+	pool := NewStorage(newTestBC(), &Config{})
+	pool.OpenJournal(filename)
+	pool.Put(a1)
+	pool.CloseJournal()
+
+	now = now.Add(24 * time.Hour)
+	pool = NewStorage(newTestBC(), &Config{})
+	err := pool.OpenJournal(filename)
+	defer pool.CloseJournal()
+
+	assert.NoError(t, err)
+	txs, _ := pool.AllTxs()
+	assert.Equal(t, []uint64{a1.ID()}, txIDs(txs))
+}
+
+func TestStorage_autoCompactJournal(t *testing.T) {
+	filename := tempJournalFile()
+	defer os.Remove(filename)
+	pool := NewStorage(newTestBC(), nil)
+	pool.OpenJournal(filename)
+	defer pool.CloseJournal()
+
+	for i := 0; i < journalCompactMin; i++ {
+		pool.Put(newTx(aliceKey, uint64(i+1)))
+		pool.RemoveTx(pool.Pop().ID())
+	}
+
+	assert.True(t, pool.journal.records <= journalCompactMin)
+	assert.Equal(t, 0, pool.Size())
+}
+
+func TestStorage_OpenJournal_poppedTxs(t *testing.T) {
+	filename := tempJournalFile()
+	defer os.Remove(filename)
+	bc := newTestBC()
+	a1, a2, b1 := newTx(aliceKey, 10), newTx(aliceKey, 20), newTx(bobKey, 15)
+
+	pool := NewStorage(bc, nil)
+	pool.OpenJournal(filename)
+	pool.Put(a1, a2, b1)
+	popped := pool.PopAll()
+	pool.RemoveTx(a1.ID()) // only a1 has been confirmed before crash
+	pool.CloseJournal()
+	bc.txs[a1.ID()] = a1
+
+	pool = NewStorage(bc, nil)
+	err := pool.OpenJournal(filename)
+	defer pool.CloseJournal()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(popped))
+	txs, _ := pool.AllTxs()
+	assert.ElementsMatch(t, []uint64{a2.ID(), b1.ID()}, txIDs(txs))
+}
+
+func TestStorage_CompactJournal_error(t *testing.T) {
+	filename := tempJournalFile()
+	defer os.Remove(filename)
+	a1, a2 := newTx(aliceKey, 10), newTx(aliceKey, 20)
+	pool := NewStorage(newTestBC(), nil)
+	pool.OpenJournal(filename)
+	pool.Put(a1)
+	os.Mkdir(filename+".tmp", 0755) // temp file can not be created
+	defer os.Remove(filename + ".tmp")
+
+	err := pool.CompactJournal()
+	pool.Put(a2)
+	pool.CloseJournal()
+
+	assert.Error(t, err)
+	pool = NewStorage(newTestBC(), nil)
+	os.Remove(filename + ".tmp")
+	assert.NoError(t, pool.OpenJournal(filename))
+	defer pool.CloseJournal()
+	txs, _ := pool.AllTxs()
+	assert.Equal(t, []uint64{a1.ID(), a2.ID()}, txIDs(txs))
+}
+
+func txIDs(txs []*chain.Transaction) (ids []uint64) {
+	for _, tx := range txs {
+		ids = append(ids, tx.ID())
+	}
+	return
+}
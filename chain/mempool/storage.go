@@ -2,28 +2,83 @@ package mempool
 
 import (
 	"bytes"
+	"container/heap"
+	"errors"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/xlog"
 )
 
+type Config struct {
+	MaxTxs       int           // max count of transactions in pool
+	MaxBytes     int           // max total size of transactions in pool
+	MaxSenderTxs int           // max count of transactions of one sender
+	TTL          time.Duration // lifetime of transaction in pool (0 - unlimited)
+}
+
+var DefaultConfig = &Config{
+	MaxTxs:       100000,
+	MaxBytes:     64 << 20,
+	MaxSenderTxs: 1000,
+	TTL:          time.Hour,
+}
+
+var (
+	ErrTxExists         = errors.New("mempool: transaction is already in blockchain")
+	ErrPoolIsFull       = errors.New("mempool: pool is full")
+	ErrTooManySenderTxs = errors.New("mempool: too many transactions of the sender")
+	ErrTxIsTooLarge     = errors.New("mempool: transaction is too large")
+)
+
+// timeNow returns current time (can be replaced in tests)
+var timeNow = time.Now
+
 type Storage struct {
-	mx  sync.RWMutex
-	txs map[uint64]*chain.Transaction
+	bc  chain.BCContext
+	cfg *Config
+
+	mx      sync.RWMutex
+	txs     map[uint64]*item   // txID => tx
+	senders map[uint64][]*item // senderID => txs ordered by nonce (see: less)
+	size    int                // total size of txs
+	journal *journal           // journal of changes (nil if journaling is off)
+	popped  map[uint64]*item   // txID => popped tx that is kept in the journal until confirmation (see: RemoveTx)
+}
+
+type item struct {
+	tx       *chain.Transaction
+	senderID uint64
+	size     int
+	fee      bignum.Int
+	expires  time.Time
 }
 
 type Info struct {
-	Size int `json:"size"`
+	Size  int `json:"size"`
+	Bytes int `json:"bytes"`
 }
 
-func NewStorage() *Storage {
+func NewStorage(bc chain.BCContext, cfg *Config) *Storage {
+	if cfg == nil {
+		cfg = DefaultConfig
+	}
 	return &Storage{
-		txs: map[uint64]*chain.Transaction{},
+		bc:      bc,
+		cfg:     cfg,
+		txs:     map[uint64]*item{},
+		senders: map[uint64][]*item{},
 	}
 }
 
 func (s *Storage) Info() (i Info) {
-	i.Size = s.Size()
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	i.Size = len(s.txs)
+	i.Bytes = s.size
 	return
 }
 
@@ -37,79 +92,409 @@ func (s *Storage) Size() int {
 func (s *Storage) SizeOf(txType int) (count int) {
 	s.mx.RLock()
 	defer s.mx.RUnlock()
-	for _, tx := range s.txs {
-		if tx.Type == txType {
+	for _, it := range s.txs {
+		if it.tx.Type == txType {
 			count++
 		}
 	}
 	return
 }
 
-func (s *Storage) Put(tx ...*chain.Transaction) (err error) {
+// Put verifies transactions and adds them to the pool.
+// It returns the first error; valid transactions are added anyway.
+func (s *Storage) Put(txs ...*chain.Transaction) (err error) {
+	for _, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		if e := s.put(tx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (s *Storage) put(tx *chain.Transaction) error {
+	if s.has(tx.ID()) {
+		return nil
+	}
+	if err := s.verify(tx); err != nil {
+		return err
+	}
+	it := &item{
+		tx:       tx,
+		senderID: tx.SenderID(),
+		size:     tx.Size(),
+		fee:      tx.Fee(),
+	}
+	if s.cfg.TTL > 0 {
+		it.expires = timeNow().Add(s.cfg.TTL)
+	}
+	if s.cfg.MaxBytes > 0 && it.size > s.cfg.MaxBytes {
+		return ErrTxIsTooLarge
+	}
+
 	s.mx.Lock()
 	defer s.mx.Unlock()
 
-	for _, tx := range tx {
-		if tx != nil {
-			s.txs[tx.ID()] = tx
+	if s.txs[tx.ID()] != nil {
+		return nil
+	}
+	s.removeExpired()
+
+	// limit of sender transactions
+	if sTxs := s.senders[it.senderID]; s.cfg.MaxSenderTxs > 0 && len(sTxs) >= s.cfg.MaxSenderTxs {
+		last := sTxs[len(sTxs)-1]
+		if !less(it, last) {
+			return ErrTooManySenderTxs
+		}
+		s.remove(last)
+	}
+
+	// limits of pool size; evict transactions with the lowest priority
+	for s.cfg.MaxTxs > 0 && len(s.txs) >= s.cfg.MaxTxs || s.cfg.MaxBytes > 0 && s.size+it.size > s.cfg.MaxBytes {
+		last := s.lowestPriority()
+		if last == nil || !priority(it, last) {
+			return ErrPoolIsFull
+		}
+		s.remove(last)
+	}
+	return s.add(it)
+}
+
+func (s *Storage) has(txID uint64) bool {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.txs[txID] != nil
+}
+
+// verify verifies signature of the transaction and checks that the transaction is not in blockchain
+func (s *Storage) verify(tx *chain.Transaction) error {
+	if s.bc == nil {
+		return nil
+	}
+	// verify copy of the transaction as unconfirmed tx (context of the transaction is not changed)
+	c := new(chain.Transaction)
+	if err := c.Decode(tx.Encode()); err != nil {
+		return err
+	}
+	c.SetBlockInfo(s.bc, 0, 0, 0)
+	if err := c.Verify(); err != nil && err != chain.ErrTxSeqGap { // future sequenced txs are queued
+		return err
+	}
+	if t, err := s.bc.TransactionByID(tx.ID()); err != nil {
+		return err
+	} else if t != nil {
+		return ErrTxExists
+	}
+	return nil
+}
+
+func (s *Storage) add(it *item) error {
+	sTxs := s.senders[it.senderID]
+	i := sort.Search(len(sTxs), func(i int) bool { return less(it, sTxs[i]) })
+	sTxs = append(sTxs, nil)
+	copy(sTxs[i+1:], sTxs[i:])
+	sTxs[i] = it
+	s.senders[it.senderID] = sTxs
+	s.txs[it.tx.ID()] = it
+	s.size += it.size
+	if s.journal != nil {
+		delete(s.popped, it.tx.ID())
+		return s.journal.put(it)
+	}
+	return nil
+}
+
+// remove removes the transaction from the pool and from the journal
+func (s *Storage) remove(it *item) {
+	if s.unlink(it) {
+		s.journalRemove(it.tx.ID())
+	}
+}
+
+// pop removes the transaction from the pool; the transaction is kept in the journal until confirmation
+func (s *Storage) pop(it *item) {
+	if s.unlink(it) && s.journal != nil {
+		s.popped[it.tx.ID()] = it
+	}
+}
+
+func (s *Storage) unlink(it *item) bool {
+	txID := it.tx.ID()
+	if s.txs[txID] != it {
+		return false
+	}
+	delete(s.txs, txID)
+	s.size -= it.size
+	sTxs := s.senders[it.senderID]
+	for i, t := range sTxs {
+		if t == it {
+			sTxs = append(sTxs[:i], sTxs[i+1:]...)
+			break
+		}
+	}
+	if len(sTxs) == 0 {
+		delete(s.senders, it.senderID)
+	} else {
+		s.senders[it.senderID] = sTxs
+	}
+	return true
+}
+
+func (s *Storage) journalRemove(txID uint64) {
+	if s.journal == nil {
+		return
+	}
+	delete(s.popped, txID)
+	if err := s.journal.remove(txID); err != nil {
+		xlog.Error.Printf("mempool> journal.remove-error: %v", err)
+	}
+	if err := s.autoCompactJournal(); err != nil {
+		xlog.Error.Printf("mempool> journal compaction-error: %v", err)
+	}
+}
+
+func (s *Storage) removeExpired() {
+	now := timeNow()
+	for _, it := range s.txs {
+		if isExpired(it.expires, now) {
+			s.remove(it)
+		}
+	}
+	for txID, it := range s.popped {
+		if isExpired(it.expires, now) {
+			s.journalRemove(txID)
+		}
+	}
+}
+
+// isExpired returns true if the expiration time is passed (zero time - transaction is not expired; TTL is unlimited)
+func isExpired(expires, now time.Time) bool {
+	return !expires.IsZero() && now.After(expires)
+}
+
+// less returns true if a precedes b in the queue of sender (older nonce first)
+func less(a, b *item) bool {
+	if a.tx.Nonce != b.tx.Nonce {
+		return a.tx.Nonce < b.tx.Nonce
+	}
+	return a.tx.ID() < b.tx.ID()
+}
+
+// priority returns true if a has higher priority than b (higher fee rate first, then older nonce)
+func priority(a, b *item) bool {
+	// compare a.fee/a.size and b.fee/b.size
+	if c := a.fee.Mul(bignum.NewInt(int64(b.size))).Cmp(b.fee.Mul(bignum.NewInt(int64(a.size)))); c != 0 {
+		return c > 0
+	}
+	return less(a, b)
+}
+
+// isReady returns false for sequenced transaction if previous transaction of the sender is not confirmed or popped.
+// seqs caches the last sequence numbers (confirmed or popped) of senders during the pop batch
+func (s *Storage) isReady(it *item, seqs map[uint64]uint64) bool {
+	seq := it.tx.SenderSeq()
+	if seq == 0 || s.bc == nil {
+		return true
+	}
+	last, ok := seqs[it.senderID]
+	if !ok {
+		last = s.bc.State().Seq(it.tx.SenderAddress())
+		seqs[it.senderID] = last
+	}
+	return seq <= last+1
+}
+
+// lowestPriority returns last transaction of the pool (the worst tail of sender queues)
+func (s *Storage) lowestPriority() (res *item) {
+	for _, sTxs := range s.senders {
+		if it := sTxs[len(sTxs)-1]; res == nil || priority(res, it) {
+			res = it
 		}
 	}
 	return
 }
 
-func (s *Storage) Pop() (tx *chain.Transaction) {
+// Pop removes and returns the transaction with the highest priority
+func (s *Storage) Pop() *chain.Transaction {
+	if txs := s.PopBatch(1, 0); len(txs) > 0 {
+		return txs[0]
+	}
+	return nil
+}
+
+// PopBatch removes and returns transactions ordered by priority
+// while count and total size of transactions is in the limits (0 - no limit)
+func (s *Storage) PopBatch(maxCount, maxBytes int) (txs []*chain.Transaction) {
 	s.mx.Lock()
 	defer s.mx.Unlock()
 
-	if len(s.txs) > 0 {
-		for txID, tx := range s.txs {
-			delete(s.txs, txID)
-			return tx
+	s.removeExpired()
+	heads := newHeadsHeap(s.senders)
+	seqs := map[uint64]uint64{}
+	size := 0
+	for heads.Len() > 0 && (maxCount <= 0 || len(txs) < maxCount) {
+		senderID := heads.ids[0]
+		it := s.senders[senderID][0]
+		if !s.isReady(it, seqs) { // next transactions of the sender are not ready too
+			heap.Pop(heads)
+			continue
+		}
+		if maxBytes > 0 && size+it.size > maxBytes {
+			break
+		}
+		if seq := it.tx.SenderSeq(); seq > 0 {
+			seqs[senderID] = seq
+		}
+		s.pop(it)
+		txs = append(txs, it.tx)
+		size += it.size
+		if len(s.senders[senderID]) > 0 {
+			heap.Fix(heads, 0)
+		} else {
+			heap.Pop(heads)
 		}
 	}
 	return
 }
 
+// PopAll removes and returns all transactions ordered by priority
 func (s *Storage) PopAll() (txs []*chain.Transaction) {
 	s.mx.Lock()
+	s.removeExpired()
 	vv := s.txs
-	s.txs = map[uint64]*chain.Transaction{}
+	s.txs = map[uint64]*item{}
+	s.senders = map[uint64][]*item{}
+	s.size = 0
+	if s.journal != nil {
+		for txID, it := range vv {
+			s.popped[txID] = it
+		}
+	}
 	s.mx.Unlock()
 
-	txs = make([]*chain.Transaction, 0, len(vv))
-	for _, tx := range vv {
-		txs = append(txs, tx)
-	}
-	return
+	return sortedTxs(vv)
 }
 
 func (s *Storage) TxsByAddress(addr []byte) (txs []*chain.Transaction, err error) {
 	s.mx.RLock()
 	defer s.mx.RUnlock()
-	for _, tx := range s.txs {
-		if bytes.Equal(tx.SenderAddress(), addr) {
-			txs = append(txs, tx)
+	items := map[uint64]*item{}
+	for txID, it := range s.txs {
+		if bytes.Equal(it.tx.SenderAddress(), addr) {
+			items[txID] = it
 		}
 	}
-	return
+	return sortedTxs(items), nil
 }
 
+// AllTxs returns all transactions ordered by priority
 func (s *Storage) AllTxs() (txs []*chain.Transaction, err error) {
 	s.mx.RLock()
 	defer s.mx.RUnlock()
-
-	txs = make([]*chain.Transaction, 0, len(s.txs))
-	for _, tx := range s.txs {
-		txs = append(txs, tx)
-	}
-	return
+	return sortedTxs(s.txs), nil
 }
 
+// RemoveTx removes transactions from the pool and popped transactions from the journal (on confirmation)
 func (s *Storage) RemoveTx(txID ...uint64) (err error) {
 	s.mx.Lock()
 	defer s.mx.Unlock()
 	for _, id := range txID {
-		delete(s.txs, id)
+		if it := s.txs[id]; it != nil {
+			s.remove(it)
+		} else if s.popped[id] != nil {
+			s.journalRemove(id)
+		}
 	}
 	return
 }
+
+// RemoveExpired removes transactions with expired TTL
+func (s *Storage) RemoveExpired() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.removeExpired()
+}
+
+// items returns items of the pool ordered by priority
+func (s *Storage) items() []*item {
+	return sortedItems(s.txs)
+}
+
+// sortedItems returns items in order of Pop: by priority of heads of sender queues
+func sortedItems(items map[uint64]*item) []*item {
+	senders := map[uint64][]*item{}
+	for _, it := range items {
+		senders[it.senderID] = append(senders[it.senderID], it)
+	}
+	var queues queuesHeap
+	for _, q := range senders {
+		sort.Slice(q, func(i, j int) bool { return less(q[i], q[j]) })
+		queues = append(queues, q)
+	}
+	heap.Init(&queues)
+	vv := make([]*item, 0, len(items))
+	for len(queues) > 0 {
+		q := queues[0]
+		vv = append(vv, q[0])
+		if len(q) > 1 {
+			queues[0] = q[1:]
+			heap.Fix(&queues, 0)
+		} else {
+			heap.Pop(&queues)
+		}
+	}
+	return vv
+}
+
+// queuesHeap is heap of sender queues by priority of their heads
+type queuesHeap [][]*item
+
+func (h queuesHeap) Len() int            { return len(h) }
+func (h queuesHeap) Less(i, j int) bool  { return priority(h[i][0], h[j][0]) }
+func (h queuesHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *queuesHeap) Push(x interface{}) { *h = append(*h, x.([]*item)) }
+func (h *queuesHeap) Pop() interface{} {
+	old := *h
+	q := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return q
+}
+
+// headsHeap is heap of senders by priority of heads of their queues
+type headsHeap struct {
+	senders map[uint64][]*item
+	ids     []uint64
+}
+
+func newHeadsHeap(senders map[uint64][]*item) *headsHeap {
+	h := &headsHeap{senders: senders, ids: make([]uint64, 0, len(senders))}
+	for senderID := range senders {
+		h.ids = append(h.ids, senderID)
+	}
+	heap.Init(h)
+	return h
+}
+
+func (h *headsHeap) Len() int { return len(h.ids) }
+func (h *headsHeap) Less(i, j int) bool {
+	return priority(h.senders[h.ids[i]][0], h.senders[h.ids[j]][0])
+}
+func (h *headsHeap) Swap(i, j int)      { h.ids[i], h.ids[j] = h.ids[j], h.ids[i] }
+func (h *headsHeap) Push(x interface{}) { h.ids = append(h.ids, x.(uint64)) }
+func (h *headsHeap) Pop() interface{} {
+	id := h.ids[len(h.ids)-1]
+	h.ids = h.ids[:len(h.ids)-1]
+	return id
+}
+
+// sortedTxs returns transactions ordered by priority
+func sortedTxs(items map[uint64]*item) []*chain.Transaction {
+	vv := sortedItems(items)
+	txs := make([]*chain.Transaction, len(vv))
+	for i, it := range vv {
+		txs[i] = it.tx
+	}
+	return txs
+}
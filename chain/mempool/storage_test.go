@@ -0,0 +1,240 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	aliceKey = crypto.NewPrivateKey()
+	bobKey   = crypto.NewPrivateKey()
+	bob      = bobKey.PublicKey().Address()
+)
+
+type testBC struct {
+	chain.BCContext
+	txs map[uint64]*chain.Transaction // confirmed txs
+}
+
+func newTestBC() *testBC {
+	return &testBC{chain.DefaultBCContext, map[uint64]*chain.Transaction{}}
+}
+
+func (bc *testBC) TransactionByID(txID uint64) (*chain.Transaction, error) {
+	return bc.txs[txID], nil
+}
+
+func newTx(from *crypto.PrivateKey, nonce uint64) *chain.Transaction {
+	return txobj.NewSimpleTransfer(nil, from.PublicKey(), from, assets.MDC, bignum.NewInt(1), 0, bob, 0, "", nonce)
+}
+
+func TestStorage_Put(t *testing.T) {
+	pool := NewStorage(newTestBC(), nil)
+	tx := newTx(aliceKey, 1)
+
+	err1 := pool.Put(tx)
+	err2 := pool.Put(tx) // the same tx
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, 1, pool.Size())
+	assert.Equal(t, tx.Size(), pool.Info().Bytes)
+}
+
+func TestStorage_Put_invalidSig(t *testing.T) {
+	pool := NewStorage(newTestBC(), nil)
+	tx := newTx(aliceKey, 1)
+	tx.Sig = bobKey.Sign(tx.Hash())
+
+	err := pool.Put(tx)
+
+	assert.Equal(t, chain.ErrInvalidTxSig, err)
+	assert.Equal(t, 0, pool.Size())
+}
+
+func TestStorage_Put_confirmedTx(t *testing.T) {
+	bc := newTestBC()
+	pool := NewStorage(bc, nil)
+	tx := newTx(aliceKey, 1)
+	bc.txs[tx.ID()] = tx
+
+	err := pool.Put(tx)
+
+	assert.Equal(t, ErrTxExists, err)
+	assert.Equal(t, 0, pool.Size())
+}
+
+func TestStorage_PopAll(t *testing.T) {
+	pool := NewStorage(newTestBC(), nil)
+	a1, a2, a3 := newTx(aliceKey, 10), newTx(aliceKey, 20), newTx(aliceKey, 30)
+	b1, b2 := newTx(bobKey, 15), newTx(bobKey, 25)
+	pool.Put(a3, b2, a1, b1, a2)
+
+	txs := pool.PopAll()
+
+	assert.Equal(t, []*chain.Transaction{a1, b1, a2, b2, a3}, txs)
+	assert.Equal(t, 0, pool.Size())
+	assert.Equal(t, 0, pool.Info().Bytes)
+}
+
+func TestStorage_Pop(t *testing.T) {
+	pool := NewStorage(newTestBC(), nil)
+	a1, a2, b1 := newTx(aliceKey, 10), newTx(aliceKey, 20), newTx(bobKey, 15)
+	pool.Put(a2, b1, a1)
+
+	assert.Equal(t, a1, pool.Pop())
+	assert.Equal(t, b1, pool.Pop())
+	assert.Equal(t, a2, pool.Pop())
+	assert.Nil(t, pool.Pop())
+}
+
+func TestStorage_Put_maxTxs(t *testing.T) {
+	pool := NewStorage(newTestBC(), &Config{MaxTxs: 2, TTL: time.Hour})
+	a1, a2, b1 := newTx(aliceKey, 10), newTx(aliceKey, 20), newTx(bobKey, 30)
+	pool.Put(a2, b1)
+
+	err1 := pool.Put(newTx(bobKey, 40)) // lowest priority
+	err2 := pool.Put(a1)                // evicts b1
+
+	assert.Equal(t, ErrPoolIsFull, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, []*chain.Transaction{a1, a2}, pool.PopAll())
+}
+
+func TestStorage_Put_maxBytes(t *testing.T) {
+	a1, a2 := newTx(aliceKey, 10), newTx(aliceKey, 20)
+	pool := NewStorage(newTestBC(), &Config{MaxBytes: a1.Size() + a2.Size() - 1, TTL: time.Hour})
+
+	err1 := pool.Put(a1)
+	err2 := pool.Put(a2)
+
+	assert.NoError(t, err1)
+	assert.Equal(t, ErrPoolIsFull, err2)
+	assert.Equal(t, 1, pool.Size())
+}
+
+func TestStorage_Put_maxSenderTxs(t *testing.T) {
+	pool := NewStorage(newTestBC(), &Config{MaxSenderTxs: 2, TTL: time.Hour})
+	a1, a2, a3 := newTx(aliceKey, 10), newTx(aliceKey, 20), newTx(aliceKey, 30)
+	b1 := newTx(bobKey, 40)
+	pool.Put(a2, a3)
+
+	err1 := pool.Put(newTx(aliceKey, 40))
+	err2 := pool.Put(a1) // evicts a3
+	err3 := pool.Put(b1)
+
+	assert.Equal(t, ErrTooManySenderTxs, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.Equal(t, []*chain.Transaction{a1, a2, b1}, pool.PopAll())
+}
+
+func TestStorage_TTL(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	pool := NewStorage(newTestBC(), &Config{TTL: time.Minute})
+	a1, a2 := newTx(aliceKey, 10), newTx(aliceKey, 20)
+	pool.Put(a1)
+	now = now.Add(30 * time.Second)
+	pool.Put(a2)
+
+	now = now.Add(31 * time.Second)
+	pool.RemoveExpired()
+
+	assert.Equal(t, 1, pool.Size())
+	assert.Equal(t, a2, pool.Pop())
+}
+
+func TestStorage_TTL_unlimited(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	pool := NewStorage(newTestBC(), &Config{})
+	a1 := newTx(aliceKey, 10)
+	pool.Put(a1)
+
+	now = now.Add(24 * time.Hour)
+	pool.RemoveExpired()
+
+	assert.Equal(t, 1, pool.Size())
+	assert.Equal(t, a1, pool.Pop())
+}
+
+func TestStorage_Put_txContext(t *testing.T) {
+	pool := NewStorage(newTestBC(), nil)
+	tx := newTx(aliceKey, 1)
+	ctx := tx.BCContext()
+
+	err := pool.Put(tx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ctx, tx.BCContext()) // context of the transaction is not changed
+}
+
+func TestStorage_RemoveTx(t *testing.T) {
+	pool := NewStorage(newTestBC(), nil)
+	a1, a2 := newTx(aliceKey, 10), newTx(aliceKey, 20)
+	pool.Put(a1, a2)
+
+	pool.RemoveTx(a1.ID())
+
+	txs, _ := pool.TxsByAddress(aliceKey.PublicKey().Address())
+	assert.Equal(t, []*chain.Transaction{a2}, txs)
+	assert.Equal(t, a2.Size(), pool.Info().Bytes)
+}
+
+func TestStorage_PopBatch(t *testing.T) {
+	pool := NewStorage(newTestBC(), nil)
+	a1, a2, a3, b1 := newTx(aliceKey, 10), newTx(aliceKey, 20), newTx(aliceKey, 30), newTx(bobKey, 15)
+	pool.Put(a3, b1, a2, a1)
+
+	txs0 := pool.PopBatch(2, 0)
+	txs1 := pool.PopBatch(0, a2.Size()+a3.Size()-1)
+	txs2 := pool.PopBatch(0, 0)
+
+	assert.Equal(t, []*chain.Transaction{a1, b1}, txs0)
+	assert.Equal(t, []*chain.Transaction{a2}, txs1)
+	assert.Equal(t, []*chain.Transaction{a3}, txs2)
+	assert.Equal(t, 0, pool.Size())
+}
+
+func newTxWithFee(from *crypto.PrivateKey, nonce uint64, fee int64) *chain.Transaction {
+	return txobj.NewTransferWithFee(nil, from.PublicKey(), from, []*txobj.TransferOutput{{
+		Asset:     assets.MDC,
+		Amount:    bignum.NewInt(1),
+		To:        bob,
+		ToChainID: chain.DefaultConfig.ChainID,
+	}}, "", bignum.NewInt(fee), nonce)
+}
+
+func TestStorage_Pop_byFeeRate(t *testing.T) {
+	pool := NewStorage(newTestBC(), nil)
+	a1, a2 := newTxWithFee(aliceKey, 10, 0), newTxWithFee(aliceKey, 20, 1e6)
+	b1, b2 := newTxWithFee(bobKey, 30, 100), newTxWithFee(bobKey, 40, 1e3)
+	pool.Put(a1, a2, b1, b2)
+
+	txs := pool.PopAll()
+
+	// higher fee rate first; txs of one sender are ordered by nonce
+	assert.Equal(t, []*chain.Transaction{b1, b2, a1, a2}, txs)
+}
+
+func TestStorage_Put_maxTxs_byFeeRate(t *testing.T) {
+	pool := NewStorage(newTestBC(), &Config{MaxTxs: 2, TTL: time.Hour})
+	a1, b1 := newTxWithFee(aliceKey, 10, 0), newTxWithFee(bobKey, 20, 100)
+	pool.Put(a1, b1)
+
+	err := pool.Put(newTxWithFee(bobKey, 30, 200)) // evicts a1 with the lowest fee rate
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pool.Size())
+	assert.False(t, pool.has(a1.ID()))
+}
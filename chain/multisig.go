@@ -0,0 +1,24 @@
+package chain
+
+import (
+	"github.com/mediacoin-pro/core/crypto"
+)
+
+// SignPartial returns signature of the transaction by one of keys of multisig account
+func (tx *Transaction) SignPartial(prv *crypto.PrivateKey) *crypto.KeySig {
+	return &crypto.KeySig{
+		Key: prv.PublicKey(),
+		Sig: prv.Sign(tx.Hash()),
+	}
+}
+
+// CombineSigs sets signatures of keys of multisig account as signature of the transaction
+func (tx *Transaction) CombineSigs(sigs ...*crypto.KeySig) {
+	tx.Sig = crypto.EncodeMultiSig(sigs)
+	tx._sigKey = nil
+}
+
+// MultiSigs returns signatures of keys of multisig account (see: CombineSigs)
+func (tx *Transaction) MultiSigs() ([]*crypto.KeySig, error) {
+	return crypto.DecodeMultiSig(tx.Sig)
+}
@@ -0,0 +1,125 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/model"
+)
+
+const protocolVersion = 1
+
+var (
+	errMessageIsTooLarge = errors.New("p2p: message is too large")
+)
+
+// Handshake is the first message of connection
+type Handshake struct {
+	Version     int       // protocol version
+	NetworkID   int       // network of chain (see: chain.Config)
+	ChainID     uint64    //
+	GenesisHash bin.Bytes // hash of genesis block of chain
+	NodeID      uint64    // random id of node (to detect self-connections and duplicates of connections)
+	ListenAddr  string    // public address of node (empty for not listening nodes)
+	LastNum     uint64    // num of last block of the node
+	LastHash    bin.Bytes // hash of last block of the node
+}
+
+// TxsMsg gossips unconfirmed transactions
+type TxsMsg struct {
+	Txs []*chain.Transaction
+}
+
+// NewBlockMsg announces new block of the node
+type NewBlockMsg struct {
+	Num  uint64
+	Hash bin.Bytes
+}
+
+// GetBlocksMsg requests range of blocks starting from Offset
+type GetBlocksMsg struct {
+	Offset uint64
+	Limit  int
+}
+
+// BlocksMsg is response on GetBlocksMsg
+type BlocksMsg struct {
+	Blocks []*chain.Block
+}
+
+var (
+	_ = model.RegisterModel(model.MsgHandshake, &Handshake{})
+	_ = model.RegisterModel(model.MsgTxs, &TxsMsg{})
+	_ = model.RegisterModel(model.MsgNewBlock, &NewBlockMsg{})
+	_ = model.RegisterModel(model.MsgGetBlocks, &GetBlocksMsg{})
+	_ = model.RegisterModel(model.MsgBlocks, &BlocksMsg{})
+)
+
+func (m *Handshake) String() string {
+	return fmt.Sprintf("[HANDSHAKE v%d net:%d chain:%d node:%x addr:%s block:%d]", m.Version, m.NetworkID, m.ChainID, m.NodeID, m.ListenAddr, m.LastNum)
+}
+
+func (m *Handshake) Encode() []byte {
+	return bin.Encode(m.Version, m.NetworkID, m.ChainID, m.GenesisHash, m.NodeID, m.ListenAddr, m.LastNum, m.LastHash)
+}
+
+func (m *Handshake) Decode(data []byte) error {
+	return bin.Decode(data, &m.Version, &m.NetworkID, &m.ChainID, &m.GenesisHash, &m.NodeID, &m.ListenAddr, &m.LastNum, &m.LastHash)
+}
+
+func (m *TxsMsg) Encode() []byte {
+	return bin.Encode(m.Txs)
+}
+
+func (m *TxsMsg) Decode(data []byte) error {
+	return bin.Decode(data, &m.Txs)
+}
+
+func (m *NewBlockMsg) Encode() []byte {
+	return bin.Encode(m.Num, m.Hash)
+}
+
+func (m *NewBlockMsg) Decode(data []byte) error {
+	return bin.Decode(data, &m.Num, &m.Hash)
+}
+
+func (m *GetBlocksMsg) Encode() []byte {
+	return bin.Encode(m.Offset, m.Limit)
+}
+
+func (m *GetBlocksMsg) Decode(data []byte) error {
+	return bin.Decode(data, &m.Offset, &m.Limit)
+}
+
+func (m *BlocksMsg) Encode() []byte {
+	return bin.Encode(m.Blocks)
+}
+
+func (m *BlocksMsg) Decode(data []byte) error {
+	return bin.Decode(data, &m.Blocks)
+}
+
+// writeMessage writes length-prefixed message (see: model.Encode)
+func writeMessage(w io.Writer, msg model.IObject) error {
+	_, err := bin.Write(w, model.Encode(msg))
+	return err
+}
+
+// readMessage reads length-prefixed message (see: model.Decode)
+func readMessage(r *bin.Reader, maxSize int) (model.IObject, error) {
+	n, err := r.ReadVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > maxSize {
+		return nil, errMessageIsTooLarge
+	}
+	buf := make([]byte, n)
+	if _, err = r.Read(buf); err != nil {
+		return nil, err
+	}
+	return model.Decode(buf)
+}
@@ -0,0 +1,490 @@
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/common/gosync"
+	"github.com/mediacoin-pro/core/common/rnd"
+	"github.com/mediacoin-pro/core/common/xlog"
+	"github.com/mediacoin-pro/core/model"
+)
+
+type Config struct {
+	ListenAddr       string        // address of incoming connections (":0" - any free port; "" - no incoming connections)
+	Seeds            []string      // addresses of peers to connect on start
+	MaxPeers         int           //
+	MaxMessageSize   int           //
+	HandshakeTimeout time.Duration //
+	SyncBatchSize    int           // max count of blocks in BlocksMsg
+	BanScore         int           // peer is banned when its score falls to -BanScore
+	BanDuration      time.Duration //
+}
+
+var DefaultConfig = &Config{
+	ListenAddr:       ":5050",
+	MaxPeers:         50,
+	MaxMessageSize:   32 << 20,
+	HandshakeTimeout: 5 * time.Second,
+	SyncBatchSize:    100,
+	BanScore:         100,
+	BanDuration:      time.Hour,
+}
+
+// penalties of peer score
+const (
+	penaltyInvalidMsg   = 20 // unknown or unexpected message
+	penaltyInvalidTx    = 10 // transaction failed on verification
+	penaltyInvalidBlock = 50 // block failed on verification
+)
+
+var (
+	ErrAlreadyStarted    = errors.New("p2p: node has been already started")
+	ErrNotStarted        = errors.New("p2p: node is not started")
+	ErrInvalidVersion    = errors.New("p2p: incompatible protocol version")
+	ErrInvalidNetwork    = errors.New("p2p: invalid network ID")
+	ErrInvalidChainID    = errors.New("p2p: invalid chain ID")
+	ErrInvalidGenesis    = errors.New("p2p: invalid genesis block")
+	ErrSelfConnection    = errors.New("p2p: connection to self")
+	ErrAlreadyConnected  = errors.New("p2p: peer has been already connected")
+	ErrTooManyPeers      = errors.New("p2p: too many peers")
+	ErrPeerBanned        = errors.New("p2p: peer is banned")
+	errInvalidHandshake  = errors.New("p2p: invalid handshake")
+	errUnexpectedMessage = errors.New("p2p: unexpected message")
+)
+
+// Node is p2p-service of blockchain node.
+// It gossips unconfirmed transactions and announcements of new blocks, and replicates blocks from peers.
+// Misbehaving peers are penalized; peers are banned (by node id and remote host) when their score falls to -BanScore.
+type Node struct {
+	cfg *Config
+	bc  *bcstore.ChainStorage
+	id  uint64
+
+	mx       sync.Mutex
+	listener net.Listener
+	sub      *bcstore.Subscription
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	peers    map[uint64]*Peer     // nodeID => peer
+	bans     map[string]time.Time // nodeID or remote host => ban expiration
+
+	mxSync sync.Mutex    // import of blocks
+	seen   *gosync.Cache // txID => true
+}
+
+func NewNode(bc *bcstore.ChainStorage, cfg *Config) *Node {
+	if cfg == nil {
+		cfg = DefaultConfig
+	}
+	return &Node{
+		cfg:   cfg,
+		bc:    bc,
+		id:    rnd.Uint64(),
+		peers: map[uint64]*Peer{},
+		bans:  map[string]time.Time{},
+		seen:  gosync.NewCache(100000),
+	}
+}
+
+// ID returns random id of the node
+func (n *Node) ID() uint64 {
+	return n.id
+}
+
+// Addr returns listening address of the node
+func (n *Node) Addr() string {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if n.listener == nil {
+		return ""
+	}
+	return n.listener.Addr().String()
+}
+
+// Start starts listening of incoming connections, connects to seeds and starts announcing of new blocks
+func (n *Node) Start() error {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if n.stop != nil {
+		return ErrAlreadyStarted
+	}
+	if n.cfg.ListenAddr != "" {
+		l, err := net.Listen("tcp", n.cfg.ListenAddr)
+		if err != nil {
+			return err
+		}
+		n.listener = l
+	}
+	n.stop = make(chan struct{})
+	n.sub = n.bc.Subscribe(bcstore.SubscribeOptions{
+		Filter: bcstore.EventFilter{Types: bcstore.EventBlock},
+		Policy: bcstore.PolicyDrop,
+	})
+	if n.listener != nil {
+		n.wg.Add(1)
+		go n.acceptLoop(n.listener)
+	}
+	n.wg.Add(1)
+	go n.announceLoop(n.sub, n.stop)
+
+	for _, addr := range n.cfg.Seeds {
+		go func(addr string) {
+			if err := n.Connect(addr); err != nil {
+				xlog.Error.Printf("p2p> connect to seed %s: %v", addr, err)
+			}
+		}(addr)
+	}
+	return nil
+}
+
+// Stop closes all connections
+func (n *Node) Stop() error {
+	n.mx.Lock()
+	if n.stop == nil {
+		n.mx.Unlock()
+		return ErrNotStarted
+	}
+	close(n.stop)
+	n.stop = nil
+	if n.listener != nil {
+		n.listener.Close()
+		n.listener = nil
+	}
+	n.sub.Unsubscribe()
+	peers := n.peers
+	n.peers = map[uint64]*Peer{}
+	n.mx.Unlock()
+
+	for _, p := range peers {
+		p.close()
+	}
+	n.wg.Wait()
+	return nil
+}
+
+// Peers returns connected peers
+func (n *Node) Peers() (peers []*Peer) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	for _, p := range n.peers {
+		peers = append(peers, p)
+	}
+	return
+}
+
+// IsBanned returns true if the node id or the host is banned (see: nodeKey, hostKey)
+func (n *Node) IsBanned(key string) bool {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	return n.isBanned(key)
+}
+
+func (n *Node) isBanned(key string) bool {
+	exp, ok := n.bans[key]
+	if ok && time.Now().After(exp) {
+		delete(n.bans, key)
+		return false
+	}
+	return ok
+}
+
+func (n *Node) ban(p *Peer) {
+	exp := time.Now().Add(n.cfg.BanDuration)
+	n.mx.Lock()
+	n.bans[nodeKey(p.ID())] = exp
+	n.bans[hostKey(p.Addr())] = exp // listen address of handshake is not trusted
+	n.mx.Unlock()
+
+	xlog.Printf("p2p> %s is banned", p)
+	p.close()
+}
+
+func nodeKey(id uint64) string {
+	return fmt.Sprintf("node:%x", id)
+}
+
+// hostKey returns ban key of the host of network address
+func hostKey(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "host:" + addr
+}
+
+// Connect connects to the node
+func (n *Node) Connect(addr string) error {
+	if n.IsBanned(hostKey(addr)) {
+		return ErrPeerBanned
+	}
+	conn, err := net.DialTimeout("tcp", addr, n.cfg.HandshakeTimeout)
+	if err != nil {
+		return err
+	}
+	return n.addPeer(conn, false)
+}
+
+func (n *Node) acceptLoop(l net.Listener) {
+	defer n.wg.Done()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		if n.IsBanned(hostKey(conn.RemoteAddr().String())) {
+			conn.Close()
+			continue
+		}
+		go func() {
+			if err := n.addPeer(conn, true); err != nil {
+				xlog.Trace.Printf("p2p> incoming connection %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+func (n *Node) handshake() *Handshake {
+	last := n.bc.LastBlockHeader()
+	return &Handshake{
+		Version:     protocolVersion,
+		NetworkID:   n.bc.Cfg.NetworkID,
+		ChainID:     n.bc.Cfg.ChainID,
+		GenesisHash: chain.GenesisBlockHeader(n.bc.Cfg).Hash(),
+		NodeID:      n.id,
+		ListenAddr:  n.Addr(),
+		LastNum:     last.Num,
+		LastHash:    last.Hash(),
+	}
+}
+
+func (n *Node) verifyHandshake(h *Handshake) error {
+	switch {
+	case h.Version != protocolVersion:
+		return ErrInvalidVersion
+	case h.NetworkID != n.bc.Cfg.NetworkID:
+		return ErrInvalidNetwork
+	case h.ChainID != n.bc.Cfg.ChainID:
+		return ErrInvalidChainID
+	case !bytes.Equal(h.GenesisHash, chain.GenesisBlockHeader(n.bc.Cfg).Hash()):
+		return ErrInvalidGenesis
+	case h.NodeID == n.id:
+		return ErrSelfConnection
+	}
+	return nil
+}
+
+// addPeer exchanges handshakes and starts serving of the connection
+func (n *Node) addPeer(conn net.Conn, inbound bool) (err error) {
+	defer func() {
+		if err != nil {
+			conn.Close()
+		}
+	}()
+	conn.SetDeadline(time.Now().Add(n.cfg.HandshakeTimeout))
+	if err = writeMessage(conn, n.handshake()); err != nil {
+		return
+	}
+	msg, err := readMessage(bin.NewReader(conn), n.cfg.MaxMessageSize)
+	if err != nil {
+		return
+	}
+	info, ok := msg.(*Handshake)
+	if !ok {
+		return errInvalidHandshake
+	}
+	if err = n.verifyHandshake(info); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	p := newPeer(n, conn, info, inbound)
+
+	n.mx.Lock()
+	switch {
+	case n.stop == nil:
+		err = ErrNotStarted
+	case n.isBanned(nodeKey(info.NodeID)), n.isBanned(hostKey(conn.RemoteAddr().String())):
+		err = ErrPeerBanned
+	case n.peers[info.NodeID] != nil:
+		err = ErrAlreadyConnected
+	case n.cfg.MaxPeers > 0 && len(n.peers) >= n.cfg.MaxPeers:
+		err = ErrTooManyPeers
+	default:
+		n.peers[info.NodeID] = p
+		n.wg.Add(2)
+	}
+	n.mx.Unlock()
+	if err != nil {
+		return
+	}
+
+	go func() {
+		defer n.wg.Done()
+		p.writeLoop()
+	}()
+	go func() {
+		defer n.wg.Done()
+		p.readLoop()
+		n.removePeer(p)
+	}()
+	xlog.Printf("p2p> %s connected (node:%x block:%d)", p, info.NodeID, info.LastNum)
+
+	n.requestBlocks(p)
+	return nil
+}
+
+func (n *Node) removePeer(p *Peer) {
+	n.mx.Lock()
+	if n.peers[p.ID()] == p {
+		delete(n.peers, p.ID())
+	}
+	n.mx.Unlock()
+}
+
+// broadcast sends the message to all peers except the peer
+func (n *Node) broadcast(msg model.IObject, except *Peer) {
+	for _, p := range n.Peers() {
+		if p != except {
+			p.send(msg)
+		}
+	}
+}
+
+// announceLoop announces new blocks of local chain to peers
+func (n *Node) announceLoop(sub *bcstore.Subscription, stop chan struct{}) {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			n.broadcast(&NewBlockMsg{Num: e.Block.Num, Hash: e.Block.Hash()}, nil)
+		}
+	}
+}
+
+// BroadcastTx puts transactions to local mempool and gossips valid transactions to peers
+func (n *Node) BroadcastTx(txs ...*chain.Transaction) (err error) {
+	var valid []*chain.Transaction
+	for _, tx := range txs {
+		if e := n.bc.Mempool.Put(tx); e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		n.seen.Set(tx.ID(), true)
+		valid = append(valid, tx)
+	}
+	if len(valid) > 0 {
+		n.broadcast(&TxsMsg{Txs: valid}, nil)
+	}
+	return
+}
+
+// requestBlocks requests next blocks if the peer has longer chain
+func (n *Node) requestBlocks(p *Peer) {
+	if last := n.bc.LastBlockHeader().Num; p.LastNum() > last {
+		p.send(&GetBlocksMsg{Offset: last, Limit: n.cfg.SyncBatchSize})
+	}
+}
+
+func (n *Node) handleMessage(p *Peer, msg model.IObject) {
+	switch m := msg.(type) {
+	case *TxsMsg:
+		n.handleTxs(p, m)
+	case *NewBlockMsg:
+		p.setLastNum(m.Num)
+		n.requestBlocks(p)
+	case *GetBlocksMsg:
+		n.handleGetBlocks(p, m)
+	case *BlocksMsg:
+		n.handleBlocks(p, m)
+	default:
+		p.penalize(penaltyInvalidMsg, errUnexpectedMessage)
+	}
+}
+
+func (n *Node) handleTxs(p *Peer, m *TxsMsg) {
+	var relay []*chain.Transaction
+	for _, tx := range m.Txs {
+		if tx == nil || n.seen.Exists(tx.ID()) {
+			continue
+		}
+		n.seen.Set(tx.ID(), true)
+		tx.SetBlockInfo(n.bc, 0, 0, 0) // unconfirmed tx of the peer
+		if err := n.bc.Mempool.Put(tx); err != nil {
+			if isInvalidTx(tx, err) {
+				p.penalize(penaltyInvalidTx, err)
+			}
+			continue
+		}
+		relay = append(relay, tx)
+	}
+	if len(relay) > 0 {
+		n.broadcast(&TxsMsg{Txs: relay}, p)
+	}
+}
+
+// isInvalidTx returns true if the transaction is rejected by fault of the peer.
+// Transactions rejected by limits of mempool or by the state (sequence, balance, already confirmed) can be stale, so they are not penalized.
+func isInvalidTx(tx *chain.Transaction, err error) bool {
+	return errors.Is(err, chain.ErrInvalidTxSig) || tx.PreVerify() != nil
+}
+
+func (n *Node) handleGetBlocks(p *Peer, m *GetBlocksMsg) {
+	limit := m.Limit
+	if limit <= 0 || limit > n.cfg.SyncBatchSize {
+		limit = n.cfg.SyncBatchSize
+	}
+	blocks, err := n.bc.GetBlocks(m.Offset, int64(limit), false)
+	if err != nil {
+		xlog.Error.Printf("p2p> GetBlocks-Error: %v", err)
+		return
+	}
+	p.send(&BlocksMsg{Blocks: blocks})
+}
+
+func (n *Node) handleBlocks(p *Peer, m *BlocksMsg) {
+	n.mxSync.Lock()
+	defer n.mxSync.Unlock()
+
+	last := n.bc.LastBlockHeader()
+	var blocks []*chain.Block
+	for _, b := range m.Blocks {
+		if b == nil || b.BlockHeader == nil {
+			p.penalize(penaltyInvalidMsg, errUnexpectedMessage)
+			return
+		}
+		if b.Num > last.Num {
+			blocks = append(blocks, b)
+		}
+	}
+	if len(blocks) == 0 {
+		return
+	}
+	if blocks[0].Num != last.Num+1 {
+		return // blocks are not continuation of local chain
+	}
+	if !bytes.Equal(blocks[0].PrevHash, last.Hash()) {
+		// todo: fork resolution (see: bcstore.Reorg)
+		xlog.Trace.Printf("p2p> %s: block#%d of another branch", p, blocks[0].Num)
+		return
+	}
+	if err := n.bc.PutBlock(blocks...); err != nil {
+		p.penalize(penaltyInvalidBlock, err)
+		return
+	}
+	p.setLastNum(blocks[len(blocks)-1].Num)
+	n.requestBlocks(p)
+}
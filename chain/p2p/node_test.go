@@ -0,0 +1,277 @@
+package p2p
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	masterKey = crypto.NewPrivateKey()
+	aliceKey  = crypto.NewPrivateKey()
+	bobKey    = crypto.NewPrivateKey()
+
+	alice = aliceKey.PublicKey().Address()
+	bob   = bobKey.PublicKey().Address()
+)
+
+func coins(n int64) bignum.Int {
+	return bignum.NewInt(n * assets.Coin)
+}
+
+func newTestConfig() *chain.Config {
+	cfg := chain.NewConfig()
+	cfg.MasterKey = masterKey.PublicKey().String()
+	return cfg
+}
+
+func newTestStorage(cfg *chain.Config) *bcstore.ChainStorage {
+	return bcstore.NewChainStorage(fmt.Sprintf("%s/test-p2p-%x.db", os.TempDir(), rand.Int()), cfg)
+}
+
+func newTransfer(bc chain.BCContext, from *crypto.PrivateKey, to []byte, amount int64) *chain.Transaction {
+	return txobj.NewSimpleTransfer(bc, from.PublicKey(), from, assets.MDC, coins(amount), 0, to, 0, "", 0)
+}
+
+// putTestBlocks puts n blocks with transfers to the chain
+func putTestBlocks(t *testing.T, bc *bcstore.ChainStorage, n int) {
+	for i := 0; i < n; i++ {
+		var tx *chain.Transaction
+		if bc.LastBlock().Num == 0 {
+			tx = txobj.NewEmission(bc, masterKey, assets.MDC, "", []*txobj.EmissionOutput{
+				{Address: alice, Amount: coins(1000)},
+			})
+		} else {
+			tx = newTransfer(bc, aliceKey, bob, 1)
+		}
+		_, err := bc.PutNewBlock([]*chain.Transaction{tx}, masterKey)
+		assert.NoError(t, err)
+	}
+}
+
+func newTestNode(t *testing.T, bc *bcstore.ChainStorage) *Node {
+	cfg := *DefaultConfig
+	cfg.ListenAddr = "127.0.0.1:0"
+	cfg.SyncBatchSize = 3
+	n := NewNode(bc, &cfg)
+	assert.NoError(t, n.Start())
+	return n
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+	}
+}
+
+// testNetwork returns nodes connected by chain: node0 - node1 - ... - nodeN
+func testNetwork(t *testing.T, chains ...*bcstore.ChainStorage) (nodes []*Node) {
+	for i, bc := range chains {
+		n := newTestNode(t, bc)
+		if i > 0 {
+			assert.NoError(t, n.Connect(nodes[i-1].Addr()))
+		}
+		nodes = append(nodes, n)
+	}
+	return
+}
+
+func stopNodes(nodes []*Node) {
+	for _, n := range nodes {
+		n.Stop()
+		n.bc.Drop()
+	}
+}
+
+func TestNode_Connect(t *testing.T) {
+	cfg := newTestConfig()
+	nodes := testNetwork(t, newTestStorage(cfg), newTestStorage(cfg))
+	defer stopNodes(nodes)
+
+	waitFor(t, func() bool { return len(nodes[0].Peers()) == 1 })
+	assert.Equal(t, 1, len(nodes[1].Peers()))
+	assert.Equal(t, nodes[0].ID(), nodes[1].Peers()[0].ID())
+	assert.Equal(t, nodes[1].ID(), nodes[0].Peers()[0].ID())
+	assert.True(t, nodes[0].Peers()[0].Inbound())
+
+	err1 := nodes[1].Connect(nodes[0].Addr())
+	err2 := nodes[0].Connect(nodes[0].Addr())
+
+	assert.Equal(t, ErrAlreadyConnected, err1)
+	assert.Equal(t, ErrSelfConnection, err2)
+}
+
+func TestNode_Connect_invalidChain(t *testing.T) {
+	cfg := newTestConfig()
+	cfg2 := newTestConfig()
+	cfg2.ChainID++
+	cfg3 := newTestConfig()
+	cfg3.NetworkID++
+	nodes := testNetwork(t, newTestStorage(cfg))
+	defer stopNodes(nodes)
+
+	n1 := newTestNode(t, newTestStorage(cfg2))
+	n1.cfg.ListenAddr = ""
+	defer stopNodes([]*Node{n1})
+	n2 := newTestNode(t, newTestStorage(cfg3))
+	defer stopNodes([]*Node{n2})
+
+	err1 := n1.Connect(nodes[0].Addr())
+	err2 := n2.Connect(nodes[0].Addr())
+
+	assert.Equal(t, ErrInvalidChainID, err1)
+	assert.Equal(t, ErrInvalidNetwork, err2)
+	assert.Equal(t, 0, len(nodes[0].Peers()))
+}
+
+func TestNode_verifyHandshake_genesis(t *testing.T) {
+	n := NewNode(newTestStorage(newTestConfig()), nil)
+	defer n.bc.Drop()
+	h := n.handshake()
+	h.NodeID++
+	assert.NoError(t, n.verifyHandshake(h))
+
+	h.GenesisHash = bin.Bytes(make([]byte, 32))
+	assert.Equal(t, ErrInvalidGenesis, n.verifyHandshake(h))
+}
+
+func TestNode_syncBlocks(t *testing.T) {
+	cfg := newTestConfig()
+	bc := newTestStorage(cfg)
+	putTestBlocks(t, bc, 10)
+
+	nodes := testNetwork(t, bc, newTestStorage(cfg), newTestStorage(cfg))
+	defer stopNodes(nodes)
+
+	// initial sync by batches
+	waitFor(t, func() bool { return nodes[2].bc.LastBlock().Num == 10 })
+	assert.Equal(t, bc.LastBlock().Hash(), nodes[1].bc.LastBlock().Hash())
+	assert.Equal(t, bc.LastBlock().Hash(), nodes[2].bc.LastBlock().Hash())
+
+	// announcement of new block
+	putTestBlocks(t, bc, 1)
+
+	waitFor(t, func() bool { return nodes[2].bc.LastBlock().Num == 11 })
+	assert.Equal(t, bc.LastBlock().Hash(), nodes[2].bc.LastBlock().Hash())
+	assert.EqualValues(t, 11, nodes[1].Peers()[0].LastNum())
+}
+
+func TestNode_BroadcastTx(t *testing.T) {
+	cfg := newTestConfig()
+	bc := newTestStorage(cfg)
+	putTestBlocks(t, bc, 1)
+	nodes := testNetwork(t, bc, newTestStorage(cfg), newTestStorage(cfg))
+	defer stopNodes(nodes)
+	waitFor(t, func() bool { return nodes[2].bc.LastBlock().Num == 1 })
+
+	tx := newTransfer(bc, aliceKey, bob, 10)
+	err := nodes[2].BroadcastTx(tx)
+
+	assert.NoError(t, err)
+	waitFor(t, func() bool { return nodes[0].bc.Mempool.Size() == 1 })
+	assert.Equal(t, 1, nodes[1].bc.Mempool.Size())
+	txs, _ := nodes[0].bc.Mempool.AllTxs()
+	assert.Equal(t, tx.ID(), txs[0].ID())
+}
+
+func TestNode_banPeer(t *testing.T) {
+	cfg := newTestConfig()
+	bc := newTestStorage(cfg)
+	putTestBlocks(t, bc, 1)
+	nodes := testNetwork(t, bc)
+	defer stopNodes(nodes)
+
+	// misbehaving peer sends invalid transactions
+	conn, err := net.Dial("tcp", nodes[0].Addr())
+	assert.NoError(t, err)
+	defer conn.Close()
+	hs := nodes[0].handshake()
+	hs.NodeID++
+	assert.NoError(t, writeMessage(conn, hs))
+	_, err = readMessage(bin.NewReader(conn), DefaultConfig.MaxMessageSize)
+	assert.NoError(t, err)
+	waitFor(t, func() bool { return len(nodes[0].Peers()) == 1 })
+
+	for i := 0; i < 10; i++ {
+		tx := newTransfer(bc, aliceKey, bob, 1)
+		tx.Sig[0]++ // invalid signature
+		writeMessage(conn, &TxsMsg{Txs: []*chain.Transaction{tx}})
+	}
+
+	waitFor(t, func() bool { return len(nodes[0].Peers()) == 0 })
+	assert.True(t, nodes[0].IsBanned(nodeKey(hs.NodeID)))
+	assert.True(t, nodes[0].IsBanned(hostKey(conn.LocalAddr().String())))
+	assert.Equal(t, 0, nodes[0].bc.Mempool.Size())
+
+	// banned host can not reconnect with another node id
+	conn2, err := net.Dial("tcp", nodes[0].Addr())
+	assert.NoError(t, err)
+	defer conn2.Close()
+	hs.NodeID++
+	writeMessage(conn2, hs)
+	_, err = readMessage(bin.NewReader(conn2), DefaultConfig.MaxMessageSize)
+	assert.Error(t, err)
+	assert.Equal(t, 0, len(nodes[0].Peers()))
+}
+
+func TestNode_staleTxs(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.SeqHeight = 1
+	bc := newTestStorage(cfg)
+	putTestBlocks(t, bc, 1)
+	var stale []*chain.Transaction // transactions with used sequence number
+	for i := 0; i < 20; i++ {
+		stale = append(stale, chain.NewSeqTx(bc, nil, aliceKey, 1, &txobj.Transfer{Outs: []*txobj.TransferOutput{{
+			Asset:     assets.MDC,
+			Amount:    coins(int64(i + 1)),
+			To:        bob,
+			ToChainID: cfg.ChainID,
+		}}}))
+	}
+	_, err := bc.PutNewBlock(stale[:1], masterKey)
+	assert.NoError(t, err)
+	nodes := testNetwork(t, bc)
+	defer stopNodes(nodes)
+
+	conn, err := net.Dial("tcp", nodes[0].Addr())
+	assert.NoError(t, err)
+	defer conn.Close()
+	hs := nodes[0].handshake()
+	hs.NodeID++
+	assert.NoError(t, writeMessage(conn, hs))
+	_, err = readMessage(bin.NewReader(conn), DefaultConfig.MaxMessageSize)
+	assert.NoError(t, err)
+	waitFor(t, func() bool { return len(nodes[0].Peers()) == 1 })
+
+	// honest peer relays transactions that are rejected by the state of node
+	for _, tx := range stale[1:] {
+		writeMessage(conn, &TxsMsg{Txs: []*chain.Transaction{tx}})
+	}
+	// and valid transaction
+	writeMessage(conn, &TxsMsg{Txs: []*chain.Transaction{
+		chain.NewSeqTx(bc, nil, aliceKey, 2, &txobj.Transfer{Outs: []*txobj.TransferOutput{{
+			Asset:     assets.MDC,
+			Amount:    coins(1),
+			To:        bob,
+			ToChainID: cfg.ChainID,
+		}}}),
+	}})
+
+	waitFor(t, func() bool { return nodes[0].bc.Mempool.Size() == 1 })
+	assert.Equal(t, 1, len(nodes[0].Peers()))
+	assert.Equal(t, 0, nodes[0].Peers()[0].Score())
+}
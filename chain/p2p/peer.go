@@ -0,0 +1,153 @@
+package p2p
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/common/xlog"
+	"github.com/mediacoin-pro/core/model"
+)
+
+const (
+	peerQueueSize = 1000
+	writeTimeout  = 10 * time.Second
+)
+
+// Peer is connection with remote node
+type Peer struct {
+	node    *Node
+	conn    net.Conn
+	info    *Handshake // handshake of remote node
+	inbound bool
+	out     chan model.IObject
+
+	mx      sync.Mutex
+	score   int
+	lastNum uint64
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newPeer(n *Node, conn net.Conn, info *Handshake, inbound bool) *Peer {
+	return &Peer{
+		node:    n,
+		conn:    conn,
+		info:    info,
+		inbound: inbound,
+		out:     make(chan model.IObject, peerQueueSize),
+		lastNum: info.LastNum,
+		closed:  make(chan struct{}),
+	}
+}
+
+func (p *Peer) String() string {
+	return "peer:" + p.conn.RemoteAddr().String()
+}
+
+// ID returns node id of the peer
+func (p *Peer) ID() uint64 {
+	return p.info.NodeID
+}
+
+// Addr returns remote address of the connection
+func (p *Peer) Addr() string {
+	return p.conn.RemoteAddr().String()
+}
+
+// Info returns handshake of the peer
+func (p *Peer) Info() *Handshake {
+	return p.info
+}
+
+// Inbound returns true if the connection has been initiated by the peer
+func (p *Peer) Inbound() bool {
+	return p.inbound
+}
+
+// Score returns reputation score of the peer; the peer is banned when its score falls to -Config.BanScore
+func (p *Peer) Score() int {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	return p.score
+}
+
+// LastNum returns num of last known block of the peer
+func (p *Peer) LastNum() uint64 {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	return p.lastNum
+}
+
+func (p *Peer) setLastNum(num uint64) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	if num > p.lastNum {
+		p.lastNum = num
+	}
+}
+
+// penalize decreases score of the peer and bans the peer when the score falls to -Config.BanScore
+func (p *Peer) penalize(penalty int, reason error) {
+	p.mx.Lock()
+	p.score -= penalty
+	score := p.score
+	p.mx.Unlock()
+
+	xlog.Trace.Printf("p2p> %s: penalty %d (score: %d): %v", p, penalty, score, reason)
+	if score <= -p.node.cfg.BanScore {
+		p.node.ban(p)
+	}
+}
+
+// send queues the message; the message is dropped if the queue is full
+func (p *Peer) send(msg model.IObject) {
+	select {
+	case p.out <- msg:
+	case <-p.closed:
+	default:
+		xlog.Trace.Printf("p2p> %s: queue is full, message is dropped", p)
+	}
+}
+
+func (p *Peer) close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+		p.conn.Close()
+	})
+}
+
+func (p *Peer) writeLoop() {
+	defer p.close()
+	for {
+		select {
+		case <-p.closed:
+			return
+		case msg := <-p.out:
+			p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := writeMessage(p.conn, msg); err != nil {
+				xlog.Trace.Printf("p2p> %s: write error: %v", p, err)
+				return
+			}
+		}
+	}
+}
+
+func (p *Peer) readLoop() {
+	defer p.close()
+	r := bin.NewReader(p.conn)
+	for {
+		msg, err := readMessage(r, p.node.cfg.MaxMessageSize)
+		if err != nil {
+			select {
+			case <-p.closed:
+			default:
+				xlog.Trace.Printf("p2p> %s: read error: %v", p, err)
+			}
+			return
+		}
+		p.node.handleMessage(p, msg)
+	}
+}
@@ -0,0 +1,183 @@
+package producer
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/common/safe"
+	"github.com/mediacoin-pro/core/common/xlog"
+	"github.com/mediacoin-pro/core/crypto"
+)
+
+type Config struct {
+	Interval     time.Duration // min interval between blocks
+	MaxBlockTxs  int           // max count of transactions in block
+	MaxBlockSize int           // max total size of transactions in block
+	MaxRetries   int           // max count of re-queue of transaction failed on execution
+}
+
+var DefaultConfig = &Config{
+	Interval:     time.Second,
+	MaxBlockTxs:  10000,
+	MaxBlockSize: 4 << 20,
+	MaxRetries:   3,
+}
+
+var (
+	ErrAlreadyStarted = errors.New("producer: service has been already started")
+	ErrNotStarted     = errors.New("producer: service is not started")
+)
+
+type Metrics struct {
+	Blocks        int64     `json:"blocks"`          // count of produced blocks
+	Txs           int64     `json:"txs"`             // count of transactions in produced blocks
+	FailedTxs     int64     `json:"failed_txs"`      // count of transactions failed on execution
+	DroppedTxs    int64     `json:"dropped_txs"`     // count of failed transactions dropped after MaxRetries
+	Errors        int64     `json:"errors"`          // count of errors of block producing
+	LastBlockNum  uint64    `json:"last_block_num"`  //
+	LastBlockTime time.Time `json:"last_block_time"` //
+	LastError     string    `json:"last_error"`      //
+}
+
+// Producer is service that periodically produces blocks from transactions of mempool
+type Producer struct {
+	bc  *bcstore.ChainStorage
+	key *crypto.PrivateKey
+	cfg *Config
+
+	mx      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	metrics Metrics
+	retries map[uint64]int // txID => count of failed executions
+}
+
+func New(bc *bcstore.ChainStorage, key *crypto.PrivateKey, cfg *Config) *Producer {
+	if cfg == nil {
+		cfg = DefaultConfig
+	}
+	return &Producer{
+		bc:      bc,
+		key:     key,
+		cfg:     cfg,
+		retries: map[uint64]int{},
+	}
+}
+
+// Start starts producing of blocks
+func (p *Producer) Start() error {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	if p.stop != nil {
+		return ErrAlreadyStarted
+	}
+	p.stop, p.done = make(chan struct{}), make(chan struct{})
+	go p.run(p.stop, p.done)
+	return nil
+}
+
+// Stop stops producing of blocks and waits for completion of current block
+func (p *Producer) Stop() error {
+	p.mx.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mx.Unlock()
+	if stop == nil {
+		return ErrNotStarted
+	}
+	close(stop)
+	<-done
+	return nil
+}
+
+func (p *Producer) IsRunning() bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	return p.stop != nil
+}
+
+func (p *Producer) Metrics() Metrics {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	return p.metrics
+}
+
+// IsScheduled returns true if the producer key is scheduled miner of next block
+func (p *Producer) IsScheduled() bool {
+	return p.key.PublicKey().Equal(chain.ScheduledMiner(p.bc, p.bc.CountBlocks()+1))
+}
+
+func (p *Producer) run(stop, done chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(p.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		if block, err := p.produce(); err != nil {
+			xlog.Error.Printf("producer> ProduceBlock-Error: %v", err)
+		} else if block != nil {
+			xlog.Printf("producer> ✅ block#%d (txs: %d)", block.Num, len(block.Txs))
+		}
+	}
+}
+
+func (p *Producer) produce() (block *chain.Block, err error) {
+	defer safe.RecoverAndReport()
+	return p.ProduceBlock()
+}
+
+// ProduceBlock produces new block from transactions of mempool (in order of priority).
+// Transactions failed on execution are returned to mempool. It returns nil if mempool is empty.
+func (p *Producer) ProduceBlock() (block *chain.Block, err error) {
+	if !p.IsScheduled() {
+		return
+	}
+	txs := p.bc.Mempool.PopBatch(p.cfg.MaxBlockTxs, p.cfg.MaxBlockSize)
+	if len(txs) == 0 {
+		return
+	}
+	block, err = p.bc.PutNewBlock(append([]*chain.Transaction{}, txs...), p.key)
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if err != nil {
+		// return all transactions to mempool
+		p.bc.Mempool.Put(txs...)
+		p.metrics.Errors++
+		p.metrics.LastError = err.Error()
+		return nil, err
+	}
+	included := map[uint64]bool{}
+	if block != nil {
+		for _, tx := range block.Txs {
+			included[tx.ID()] = true
+			delete(p.retries, tx.ID())
+		}
+		p.metrics.Blocks++
+		p.metrics.Txs += int64(len(block.Txs))
+		p.metrics.LastBlockNum = block.Num
+		p.metrics.LastBlockTime = time.Now()
+	}
+	// re-queue failed transactions
+	for _, tx := range txs {
+		if txID := tx.ID(); !included[txID] {
+			p.metrics.FailedTxs++
+			if p.retries[txID]++; p.retries[txID] > p.cfg.MaxRetries {
+				delete(p.retries, txID)
+				p.metrics.DroppedTxs++
+			} else if p.bc.Mempool.Put(tx) != nil {
+				delete(p.retries, txID)
+				p.metrics.DroppedTxs++
+			}
+		}
+	}
+	return
+}
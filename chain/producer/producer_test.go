@@ -0,0 +1,169 @@
+package producer
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	masterKey = crypto.NewPrivateKey()
+	aliceKey  = crypto.NewPrivateKey()
+	bobKey    = crypto.NewPrivateKey()
+	catKey    = crypto.NewPrivateKey()
+
+	alice = aliceKey.PublicKey().Address()
+	bob   = bobKey.PublicKey().Address()
+	cat   = catKey.PublicKey().Address()
+)
+
+func coins(n int64) bignum.Int {
+	return bignum.NewInt(n * assets.Coin)
+}
+
+// newTestChain returns temporary storage with genesis emission (block#1)
+func newTestChain(t *testing.T) *bcstore.ChainStorage {
+	cfg := chain.NewConfig()
+	cfg.MasterKey = masterKey.PublicKey().String()
+	bc := bcstore.NewChainStorage(fmt.Sprintf("%s/test-producer-%x.db", os.TempDir(), rand.Int()), cfg)
+	_, err := bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewEmission(bc, masterKey, assets.MDC, "", []*txobj.EmissionOutput{
+			{Address: alice, Amount: coins(1000)},
+		}),
+	}, masterKey)
+	assert.NoError(t, err)
+	return bc
+}
+
+func newTransfer(bc chain.BCContext, from *crypto.PrivateKey, to []byte, amount int64, nonce uint64) *chain.Transaction {
+	return txobj.NewSimpleTransfer(bc, from.PublicKey(), from, assets.MDC, coins(amount), 0, to, 0, "", nonce)
+}
+
+func balance(bc *bcstore.ChainStorage, addr []byte) int64 {
+	v, _, _ := bc.GetBalance(addr, assets.MDC)
+	return v.Int64() / assets.Coin
+}
+
+func TestProducer_ProduceBlock(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	p := New(bc, masterKey, nil)
+	bc.PublishTx(newTransfer(bc, aliceKey, bob, 10, 0))
+	bc.PublishTx(newTransfer(bc, aliceKey, cat, 20, 0))
+
+	block, err := p.ProduceBlock()
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, block.Num)
+	assert.Equal(t, 2, len(block.Txs))
+	assert.EqualValues(t, 10, balance(bc, bob))
+	assert.EqualValues(t, 20, balance(bc, cat))
+	assert.Equal(t, 0, bc.Mempool.Size())
+	m := p.Metrics()
+	assert.EqualValues(t, 1, m.Blocks)
+	assert.EqualValues(t, 2, m.Txs)
+	assert.EqualValues(t, 2, m.LastBlockNum)
+}
+
+func TestProducer_ProduceBlock_emptyMempool(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	p := New(bc, masterKey, nil)
+
+	block, err := p.ProduceBlock()
+
+	assert.NoError(t, err)
+	assert.Nil(t, block)
+	assert.EqualValues(t, 1, bc.CountBlocks())
+}
+
+func TestProducer_ProduceBlock_maxBlockTxs(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	p := New(bc, masterKey, &Config{Interval: time.Second, MaxBlockTxs: 2})
+	for i := 0; i < 3; i++ {
+		bc.PublishTx(newTransfer(bc, aliceKey, bob, 1, 0))
+	}
+
+	block, err := p.ProduceBlock()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(block.Txs))
+	assert.Equal(t, 1, bc.Mempool.Size())
+}
+
+func TestProducer_ProduceBlock_requeueFailedTxs(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	p := New(bc, masterKey, &Config{Interval: time.Second, MaxRetries: 1})
+	tx1 := newTransfer(bc, catKey, bob, 5, 100)    // cat has no coins yet
+	tx2 := newTransfer(bc, aliceKey, cat, 10, 200) //
+	tx3 := newTransfer(bc, bobKey, cat, 100, 300)  // bob never has enough coins
+	bc.PublishTx(tx1)
+	bc.PublishTx(tx2)
+	bc.PublishTx(tx3)
+
+	block1, err1 := p.ProduceBlock()
+	block2, err2 := p.ProduceBlock()
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, 1, len(block1.Txs))
+	assert.Equal(t, tx2.ID(), block1.Txs[0].ID())
+	assert.Equal(t, 1, len(block2.Txs))
+	assert.Equal(t, tx1.ID(), block2.Txs[0].ID())
+	assert.Equal(t, 0, bc.Mempool.Size()) // tx3 has been dropped
+	assert.EqualValues(t, 5, balance(bc, cat))
+	m := p.Metrics()
+	assert.EqualValues(t, 3, m.FailedTxs)
+	assert.EqualValues(t, 1, m.DroppedTxs)
+}
+
+func TestProducer_StartStop(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	p := New(bc, masterKey, &Config{Interval: 10 * time.Millisecond})
+	bc.PublishTx(newTransfer(bc, aliceKey, bob, 10, 0))
+
+	err0 := p.Start()
+	err1 := p.Start()
+	for i := 0; i < 200 && bc.CountBlocks() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	running := p.IsRunning()
+	err2 := p.Stop()
+	err3 := p.Stop()
+
+	assert.NoError(t, err0)
+	assert.Equal(t, ErrAlreadyStarted, err1)
+	assert.True(t, running)
+	assert.NoError(t, err2)
+	assert.Equal(t, ErrNotStarted, err3)
+	assert.False(t, p.IsRunning())
+	assert.EqualValues(t, 2, bc.CountBlocks())
+	assert.EqualValues(t, 1, p.Metrics().Blocks)
+}
+
+func TestProducer_ProduceBlock_notScheduled(t *testing.T) {
+	bc := newTestChain(t)
+	defer bc.Drop()
+	p := New(bc, aliceKey, nil) // alice is not miner
+	bc.PublishTx(newTransfer(bc, aliceKey, bob, 10, 0))
+
+	block, err := p.ProduceBlock()
+
+	assert.NoError(t, err)
+	assert.Nil(t, block)
+	assert.False(t, p.IsScheduled())
+	assert.Equal(t, 1, bc.Mempool.Size())
+}
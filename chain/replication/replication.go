@@ -3,82 +3,137 @@ package replication
 import (
 	"time"
 
+	"github.com/mediacoin-pro/core/chain"
 	"github.com/mediacoin-pro/core/chain/bcstore"
 	"github.com/mediacoin-pro/core/common/safe"
 	"github.com/mediacoin-pro/core/common/xlog"
-	"github.com/mediacoin-pro/node/rest"
 )
 
-type Service struct {
-	client *rest.Client
-	bc     *bcstore.ChainStorage
+// Node is client of remote node (see: restnode package).
+// Blocks of the node are replicated if no other sources are set; transactions of local mempool are published to the node.
+type Node interface {
+	GetBlocks(offset uint64, limit int) ([]*chain.Block, error)
+	PutTx(tx *chain.Transaction) error
+}
+
+type Config struct {
+	BatchSize  int           // count of blocks per request
+	Workers    int           // count of parallel requests of block bodies
+	MaxRetries int           // max count of retries of failed request
+	MinBackoff time.Duration // delay before first retry; the delay is doubled on each next retry
+	MaxBackoff time.Duration // max delay between retries
+
+	BlocksPollInterval  time.Duration // delay between requests of new blocks when the chain is synced (5s by default)
+	MempoolPollInterval time.Duration // delay between checks of empty mempool (1s by default)
+}
+
+var DefaultConfig = &Config{
+	BatchSize:  100,
+	Workers:    4,
+	MaxRetries: 5,
+	MinBackoff: 100 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+
+	BlocksPollInterval:  5 * time.Second,
+	MempoolPollInterval: time.Second,
+}
+
+// backoff returns delay before the retry
+func (c *Config) backoff(attempt int) time.Duration {
+	if attempt > 30 {
+		return c.MaxBackoff
+	}
+	if d := c.MinBackoff << uint(attempt); d > 0 && d < c.MaxBackoff {
+		return d
+	}
+	return c.MaxBackoff
+}
+
+// pollInterval returns delay between requests of idle source
+func pollInterval(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
 }
 
-const blocksReplicationBatchSize = 100
+type Service struct {
+	node    Node
+	bc      *bcstore.ChainStorage
+	cfg     *Config
+	sources []BlockSource
+}
 
-func Start(bc *bcstore.ChainStorage) *Service {
-	s := NewService(nil, bc)
+// Start starts replication of blocks from the sources (the node by default) and publishing of mempool txs to the node
+func Start(node Node, bc *bcstore.ChainStorage, sources ...BlockSource) *Service {
+	s := NewService(node, bc, nil, sources...)
 	s.StartReplication()
 	return s
 }
 
-func NewService(c *rest.Client, bc *bcstore.ChainStorage) *Service {
-	if c == nil {
-		c = rest.NewClient("")
+// NewService returns replication service; node can be nil if sources are set (mempool txs are not published)
+func NewService(node Node, bc *bcstore.ChainStorage, cfg *Config, sources ...BlockSource) *Service {
+	if cfg == nil {
+		cfg = DefaultConfig
+	}
+	if len(sources) == 0 && node != nil {
+		sources = []BlockSource{NewNodeSource(node)}
 	}
 	return &Service{
-		client: c,
-		bc:     bc,
+		node:    node,
+		bc:      bc,
+		cfg:     cfg,
+		sources: sources,
 	}
 }
 
 func (s *Service) StartReplication() {
 	go s.startBlockchainReplication()
-	go s.startMempoolReplication()
+	if s.node != nil {
+		go s.startMempoolReplication()
+	}
 }
 
 func (s *Service) startBlockchainReplication() {
-	for {
-		ok, err := s.loadBlocksBatch(s.bc.LastBlock().Num, blocksReplicationBatchSize)
+	for attempt := 0; ; {
+		n, err := s.syncBlocks()
 		if err != nil {
-			xlog.Error.Printf("replication> loadBlocksBatch Error: %v", err)
+			xlog.Error.Printf("replication> Sync Error: %v", err)
+			time.Sleep(s.cfg.backoff(attempt))
+			attempt++
+			continue
 		}
-		if !ok || err != nil {
-			time.Sleep(5 * time.Second)
+		attempt = 0
+		if n == 0 { // no new blocks
+			time.Sleep(pollInterval(s.cfg.BlocksPollInterval, 5*time.Second))
 		}
 	}
 }
 
-func (s *Service) loadBlocksBatch(blockOffset uint64, batchSize int) (ok bool, err error) {
+func (s *Service) syncBlocks() (n int, err error) {
 	defer safe.RecoverAndReport()
 
-	//xlog.Printf("replication> loading blocks: offset:%v size:%d ...", blockOffset, batchSize)
-	blocks, err := s.client.GetBlocks(blockOffset, batchSize)
-	//xlog.PrintfErr("replication> loaded blocks: %v", len(blocks), err)
-	if err != nil {
-		xlog.Error.Printf("replication> client.GetBlocks-Error: %v", err)
-		return
+	if n, err = s.Sync(); n > 0 {
+		xlog.Printf("replication> ✅ replicated block#%d ", s.bc.LastBlock().Num)
 	}
-	if len(blocks) == 0 {
-		return
-	}
-	if err = s.bc.PutBlock(blocks...); err != nil {
-		xlog.Error.Printf("replication> bc.PutBlocks-Error: %v", err)
-		return
-	}
-	xlog.Printf("replication> ✅ replicated block#%d ", blocks[len(blocks)-1].Num)
-	return true, nil
+	return
 }
 
 func (s *Service) startMempoolReplication() {
 
 	// todo: (it`s temporary scheme) refactor me! use decentralize replication;
 
-	for ; ; time.Sleep(100 * time.Millisecond) {
-		if ok, err := s.putMempoolTxs(); err != nil {
-			xlog.Error.Printf("replication> loadBlocksBatch-Error: %v", err)
-		} else if !ok {
-			time.Sleep(time.Second)
+	for attempt := 0; ; {
+		ok, err := s.putMempoolTxs()
+		if err != nil {
+			xlog.Error.Printf("replication> putMempoolTxs-Error: %v", err)
+			time.Sleep(s.cfg.backoff(attempt))
+			attempt++
+			continue
+		}
+		attempt = 0
+		if !ok { // empty mempool
+			time.Sleep(pollInterval(s.cfg.MempoolPollInterval, time.Second))
 		}
 	}
 }
@@ -94,8 +149,7 @@ func (s *Service) putMempoolTxs() (ok bool, err error) {
 
 	//-- put to remote node
 	for _, tx := range txs {
-		if err = s.client.PutTx(tx); err != nil {
-			xlog.Error.Printf("replication> client.PutTx-Error: %v", err)
+		if err = s.node.PutTx(tx); err != nil {
 			return
 		}
 		//-- remove tx from mempool
@@ -0,0 +1,213 @@
+package replication
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/chain/txobj"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	masterKey = crypto.NewPrivateKey()
+	aliceKey  = crypto.NewPrivateKey()
+	bobKey    = crypto.NewPrivateKey()
+
+	alice = aliceKey.PublicKey().Address()
+	bob   = bobKey.PublicKey().Address()
+)
+
+var testConfig = &Config{
+	BatchSize:  3,
+	Workers:    2,
+	MaxRetries: 3,
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 10 * time.Millisecond,
+}
+
+func coins(n int64) bignum.Int {
+	return bignum.NewInt(n * assets.Coin)
+}
+
+func newTestConfig() *chain.Config {
+	cfg := chain.NewConfig()
+	cfg.MasterKey = masterKey.PublicKey().String()
+	return cfg
+}
+
+func newTestStorage() *bcstore.ChainStorage {
+	return bcstore.NewChainStorage(fmt.Sprintf("%s/test-replication-%x.db", os.TempDir(), rand.Int()), newTestConfig())
+}
+
+func newTransfer(bc chain.BCContext, amount int64) *chain.Transaction {
+	return txobj.NewSimpleTransfer(bc, aliceKey.PublicKey(), aliceKey, assets.MDC, coins(amount), 0, bob, 0, "", 0)
+}
+
+// newTestChain returns storage with n blocks
+func newTestChain(t *testing.T, n int) *bcstore.ChainStorage {
+	bc := newTestStorage()
+	_, err := bc.PutNewBlock([]*chain.Transaction{
+		txobj.NewEmission(bc, masterKey, assets.MDC, "", []*txobj.EmissionOutput{
+			{Address: alice, Amount: coins(1000)},
+		}),
+	}, masterKey)
+	assert.NoError(t, err)
+	for i := 1; i < n; i++ {
+		_, err = bc.PutNewBlock([]*chain.Transaction{newTransfer(bc, 1), newTransfer(bc, 2)}, masterKey)
+		assert.NoError(t, err)
+	}
+	return bc
+}
+
+// faultySource returns blocks without last transaction
+type faultySource struct {
+	BlockSource
+	requests int64
+}
+
+func (s *faultySource) String() string {
+	return "faulty"
+}
+
+func (s *faultySource) Blocks(offset uint64, limit int) ([]*chain.Block, error) {
+	atomic.AddInt64(&s.requests, 1)
+	blocks, err := s.BlockSource.Blocks(offset, limit)
+	for _, b := range blocks {
+		b.Txs = b.Txs[:len(b.Txs)-1]
+	}
+	return blocks, err
+}
+
+func TestService_Sync(t *testing.T) {
+	src := newTestChain(t, 20)
+	defer src.Drop()
+	bc := newTestStorage()
+	defer bc.Drop()
+	s := NewService(nil, bc, testConfig, NewLocalSource(src))
+
+	n, err := s.Sync()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 20, n)
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+
+	// new blocks
+	_, err = src.PutNewBlock([]*chain.Transaction{newTransfer(src, 1)}, masterKey)
+	assert.NoError(t, err)
+
+	n1, err1 := s.Sync()
+	n2, err2 := s.Sync()
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, 1, n1)
+	assert.Equal(t, 0, n2)
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+}
+
+func TestService_Sync_multipleSources(t *testing.T) {
+	src := newTestChain(t, 20)
+	defer src.Drop()
+
+	// http-peer
+	srv := httptest.NewServer(HTTPHandler(NewLocalSource(src)))
+	defer srv.Close()
+
+	// dump file
+	dumpFile := src.Dir + ".dump"
+	defer os.Remove(dumpFile)
+	assert.NoError(t, src.Dump(dumpFile))
+	dump, err := OpenDumpSource(dumpFile, src.Cfg)
+	assert.NoError(t, err)
+	defer dump.Close()
+
+	faulty := &faultySource{BlockSource: NewLocalSource(src)}
+
+	bc := newTestStorage()
+	defer bc.Drop()
+	s := NewService(nil, bc, testConfig, NewHTTPSource(srv.URL), faulty, dump)
+
+	n, err := s.Sync()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 20, n)
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+	assert.True(t, atomic.LoadInt64(&faulty.requests) > 0)
+}
+
+func TestService_Sync_invalidSource(t *testing.T) {
+	src := newTestChain(t, 5)
+	defer src.Drop()
+	bc := newTestStorage()
+	defer bc.Drop()
+	s := NewService(nil, bc, testConfig, &faultySource{BlockSource: NewLocalSource(src)})
+
+	n, err := s.Sync()
+
+	assert.ErrorIs(t, err, chain.ErrInvalidTxsMerkleRoot)
+	assert.Equal(t, 0, n)
+	assert.EqualValues(t, 0, bc.LastBlock().Num)
+}
+
+func TestHTTPSource(t *testing.T) {
+	src := newTestChain(t, 5)
+	defer src.Drop()
+	srv := httptest.NewServer(HTTPHandler(NewLocalSource(src)))
+	defer srv.Close()
+	hs := NewHTTPSource(srv.URL)
+
+	headers, err1 := hs.BlockHeaders(2, 10)
+	blocks, err2 := hs.Blocks(0, 2)
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, 3, len(headers))
+	assert.EqualValues(t, 3, headers[0].Num)
+	assert.Equal(t, src.LastBlock().Hash(), headers[2].Hash())
+	assert.Equal(t, 2, len(blocks))
+	assert.EqualValues(t, 1, blocks[0].Num)
+	assert.NoError(t, blocks[1].VerifyTxRoot())
+}
+
+// testNode is remote node serving blocks of local storage
+type testNode struct {
+	bc       *bcstore.ChainStorage
+	requests int64
+	txs      []*chain.Transaction
+}
+
+func (n *testNode) GetBlocks(offset uint64, limit int) ([]*chain.Block, error) {
+	atomic.AddInt64(&n.requests, 1)
+	return n.bc.GetBlocks(offset, int64(limit), false)
+}
+
+func (n *testNode) PutTx(tx *chain.Transaction) error {
+	n.txs = append(n.txs, tx)
+	return nil
+}
+
+func TestService_Sync_node(t *testing.T) {
+	src := newTestChain(t, 20)
+	defer src.Drop()
+	bc := newTestStorage()
+	defer bc.Drop()
+	node := &testNode{bc: src}
+	s := NewService(node, bc, testConfig)
+
+	n, err := s.Sync()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 20, n)
+	assert.Equal(t, src.LastBlock().Hash(), bc.LastBlock().Hash())
+	assert.EqualValues(t, 7, atomic.LoadInt64(&node.requests)) // blocks are loaded once with headers (+ last empty request)
+}
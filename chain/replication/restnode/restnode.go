@@ -0,0 +1,23 @@
+// Package restnode is adapter of rest-node client for replication service
+package restnode
+
+import (
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/chain/replication"
+	"github.com/mediacoin-pro/node/rest"
+)
+
+var _ replication.Node = (*rest.Client)(nil)
+
+// Start starts replication of blocks from the sources (rest-node by default) and publishing of mempool txs to rest-node
+func Start(bc *bcstore.ChainStorage, sources ...replication.BlockSource) *replication.Service {
+	return replication.Start(rest.NewClient(""), bc, sources...)
+}
+
+// NewService returns replication service of rest-node (default rest-node if c is nil)
+func NewService(c *rest.Client, bc *bcstore.ChainStorage, cfg *replication.Config, sources ...replication.BlockSource) *replication.Service {
+	if c == nil {
+		c = rest.NewClient("")
+	}
+	return replication.NewService(c, bc, cfg, sources...)
+}
@@ -0,0 +1,228 @@
+package replication
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/bcstore"
+	"github.com/mediacoin-pro/core/common/bin"
+)
+
+// BlockSource is source of blocks for replication
+type BlockSource interface {
+	// BlockHeaders returns headers of blocks after the block offset
+	BlockHeaders(offset uint64, limit int) ([]*chain.BlockHeader, error)
+
+	// Blocks returns blocks after the block offset
+	Blocks(offset uint64, limit int) ([]*chain.Block, error)
+}
+
+// LocalSource replicates blocks from local chain storage
+type LocalSource struct {
+	bc *bcstore.ChainStorage
+}
+
+func NewLocalSource(bc *bcstore.ChainStorage) *LocalSource {
+	return &LocalSource{bc}
+}
+
+func (s *LocalSource) String() string {
+	return "local:" + s.bc.Dir
+}
+
+func (s *LocalSource) BlockHeaders(offset uint64, limit int) (headers []*chain.BlockHeader, err error) {
+	err = s.bc.FetchBlockHeaders(offset, int64(limit), false, func(h *chain.BlockHeader) error {
+		headers = append(headers, h)
+		return nil
+	})
+	return
+}
+
+func (s *LocalSource) Blocks(offset uint64, limit int) ([]*chain.Block, error) {
+	return s.bc.GetBlocks(offset, int64(limit), false)
+}
+
+// DumpSource replicates blocks from the dump file of chain storage (see: bcstore.ChainStorage.Dump)
+type DumpSource struct {
+	LocalSource
+	filePath string
+}
+
+// OpenDumpSource restores the dump file to temporary storage
+func OpenDumpSource(filePath string, cfg *chain.Config) (*DumpSource, error) {
+	dir, err := os.MkdirTemp("", "replication-dump-")
+	if err != nil {
+		return nil, err
+	}
+	bc, err := bcstore.RestoreDump(filePath, dir, cfg)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &DumpSource{LocalSource{bc}, filePath}, nil
+}
+
+func (s *DumpSource) String() string {
+	return "dump:" + s.filePath
+}
+
+// Close removes temporary storage of the dump
+func (s *DumpSource) Close() error {
+	return s.bc.Drop()
+}
+
+// HTTPSource replicates blocks from HTTP-peer (see: HTTPHandler)
+type HTTPSource struct {
+	url    string
+	client *http.Client
+}
+
+const httpSourceTimeout = 30 * time.Second
+
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{
+		url:    strings.TrimRight(url, "/"),
+		client: &http.Client{Timeout: httpSourceTimeout},
+	}
+}
+
+func (s *HTTPSource) String() string {
+	return "http:" + s.url
+}
+
+func (s *HTTPSource) BlockHeaders(offset uint64, limit int) (headers []*chain.BlockHeader, err error) {
+	err = s.get("/headers", offset, limit, &headers)
+	return
+}
+
+func (s *HTTPSource) Blocks(offset uint64, limit int) (blocks []*chain.Block, err error) {
+	err = s.get("/blocks", offset, limit, &blocks)
+	return
+}
+
+func (s *HTTPSource) get(path string, offset uint64, limit int, v interface{}) error {
+	q := url.Values{}
+	q.Set("offset", strconv.FormatUint(offset, 10))
+	q.Set("limit", strconv.Itoa(limit))
+	resp, err := s.client.Get(s.url + path + "?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("replication.HTTPSource-Error: %s: %s", resp.Status, msg)
+	}
+	return bin.Read(resp.Body, v)
+}
+
+// maxHTTPLimit is max count of blocks in response of HTTPHandler
+const maxHTTPLimit = 1000
+
+// HTTPHandler serves blocks of the storage for HTTPSource
+func HTTPHandler(src BlockSource) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		serveHTTP(w, r, func(offset uint64, limit int) (interface{}, error) {
+			return src.BlockHeaders(offset, limit)
+		})
+	})
+	mux.HandleFunc("/blocks", func(w http.ResponseWriter, r *http.Request) {
+		serveHTTP(w, r, func(offset uint64, limit int) (interface{}, error) {
+			return src.Blocks(offset, limit)
+		})
+	})
+	return mux
+}
+
+func serveHTTP(w http.ResponseWriter, r *http.Request, fn func(offset uint64, limit int) (interface{}, error)) {
+	offset, err := strconv.ParseUint(r.FormValue("offset"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.FormValue("limit"))
+	if limit <= 0 || limit > maxHTTPLimit {
+		limit = maxHTTPLimit
+	}
+	v, err := fn(offset, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	bin.Write(w, v)
+}
+
+// NodeSource replicates blocks from remote node.
+// The node returns headers with block bodies, so blocks loaded with headers are kept for the next request of bodies.
+type NodeSource struct {
+	node Node
+
+	mx     sync.Mutex
+	blocks map[uint64]*chain.Block // blockNum => block loaded with header
+}
+
+// maxNodeSourceCache is max count of blocks kept between requests of headers and bodies
+const maxNodeSourceCache = 10000
+
+func NewNodeSource(node Node) *NodeSource {
+	return &NodeSource{
+		node:   node,
+		blocks: map[uint64]*chain.Block{},
+	}
+}
+
+func (s *NodeSource) String() string {
+	return "node"
+}
+
+func (s *NodeSource) BlockHeaders(offset uint64, limit int) (headers []*chain.BlockHeader, err error) {
+	blocks, err := s.node.GetBlocks(offset, limit)
+	if err != nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if len(s.blocks)+len(blocks) > maxNodeSourceCache {
+		s.blocks = map[uint64]*chain.Block{}
+	}
+	for _, b := range blocks {
+		if b != nil && b.BlockHeader != nil {
+			s.blocks[b.Num] = b
+			headers = append(headers, b.BlockHeader)
+		}
+	}
+	return
+}
+
+func (s *NodeSource) Blocks(offset uint64, limit int) ([]*chain.Block, error) {
+	if blocks := s.cachedBlocks(offset, limit); blocks != nil {
+		return blocks, nil
+	}
+	return s.node.GetBlocks(offset, limit)
+}
+
+// cachedBlocks returns and removes the blocks from cache (nil if some blocks are not loaded)
+func (s *NodeSource) cachedBlocks(offset uint64, limit int) []*chain.Block {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	blocks := make([]*chain.Block, limit)
+	for i := range blocks {
+		if blocks[i] = s.blocks[offset+uint64(i)+1]; blocks[i] == nil {
+			return nil
+		}
+	}
+	for _, b := range blocks {
+		delete(s.blocks, b.Num)
+	}
+	return blocks
+}
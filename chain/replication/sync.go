@@ -0,0 +1,140 @@
+package replication
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mediacoin-pro/core/chain"
+)
+
+var (
+	errInvalidBlocksResponse = errors.New("replication: blocks do not match verified headers")
+	errNoSources             = errors.New("replication: no block sources")
+)
+
+// Sync replicates new blocks from the sources.
+// Headers of new blocks are downloaded and verified first (see BlockHeader.VerifyHeader),
+// then block bodies are downloaded from all sources in parallel, checked by the verified headers and merkle roots of
+// transactions, and put to storage in order. Failed requests are retried with exponential backoff on next sources.
+// It returns count of replicated blocks.
+func (s *Service) Sync() (n int, err error) {
+	if len(s.sources) == 0 {
+		return 0, errNoSources
+	}
+	last := s.bc.LastBlockHeader()
+	for {
+		headers, err := s.syncHeaders(last)
+		if err != nil || len(headers) == 0 {
+			return n, err
+		}
+		for _, blocks := range s.syncBodies(headers) {
+			if blocks.err != nil {
+				return n, blocks.err
+			}
+			if err = s.bc.PutBlock(blocks.blocks...); err != nil {
+				return n, err
+			}
+			n += len(blocks.blocks)
+		}
+		if len(headers) < s.cfg.BatchSize*s.cfg.Workers {
+			return n, nil
+		}
+		last = headers[len(headers)-1]
+	}
+}
+
+// syncHeaders downloads and verifies up to Workers batches of headers after the block
+func (s *Service) syncHeaders(last *chain.BlockHeader) (headers []*chain.BlockHeader, err error) {
+	for i := 0; i < s.cfg.Workers; i++ {
+		var batch []*chain.BlockHeader
+		err = s.retry(func(src BlockSource) (err error) {
+			if batch, err = src.BlockHeaders(last.Num, s.cfg.BatchSize); err != nil {
+				return
+			}
+			pre := last
+			for _, h := range batch {
+				if err = h.VerifyHeader(pre, s.bc.Cfg); err != nil {
+					return fmt.Errorf("replication: invalid header of block#%d from %v: %w", h.Num, src, err)
+				}
+				pre = h
+			}
+			return
+		})
+		if err != nil {
+			return
+		}
+		headers = append(headers, batch...)
+		if len(batch) < s.cfg.BatchSize {
+			break
+		}
+		last = batch[len(batch)-1]
+	}
+	return
+}
+
+type blocksBatch struct {
+	blocks []*chain.Block
+	err    error
+}
+
+// syncBodies downloads bodies of blocks by batches in parallel
+func (s *Service) syncBodies(headers []*chain.BlockHeader) []*blocksBatch {
+	var res []*blocksBatch
+	var wg sync.WaitGroup
+	for i := 0; i < len(headers); i += s.cfg.BatchSize {
+		end := i + s.cfg.BatchSize
+		if end > len(headers) {
+			end = len(headers)
+		}
+		hh := headers[i:end]
+		batch := &blocksBatch{}
+		res = append(res, batch)
+		wg.Add(1)
+		go func(srcIdx int) {
+			defer wg.Done()
+			batch.err = s.retryFrom(srcIdx, func(src BlockSource) (err error) {
+				batch.blocks, err = downloadBodies(src, hh)
+				return
+			})
+		}(len(res) - 1)
+	}
+	wg.Wait()
+	return res
+}
+
+// downloadBodies downloads blocks of the verified headers
+func downloadBodies(src BlockSource, headers []*chain.BlockHeader) ([]*chain.Block, error) {
+	blocks, err := src.Blocks(headers[0].Num-1, len(headers))
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) != len(headers) {
+		return nil, errInvalidBlocksResponse
+	}
+	for i, b := range blocks {
+		if b == nil || b.BlockHeader == nil || !bytes.Equal(b.BlockHeader.Encode(), headers[i].Encode()) {
+			return nil, errInvalidBlocksResponse
+		}
+		if err = b.VerifyTxRoot(); err != nil {
+			return nil, fmt.Errorf("replication: invalid block#%d from %v: %w", b.Num, src, err)
+		}
+	}
+	return blocks, nil
+}
+
+func (s *Service) retry(fn func(src BlockSource) error) error {
+	return s.retryFrom(0, fn)
+}
+
+// retryFrom calls fn for sources starting from the source srcIdx; on error the next source is used after backoff delay
+func (s *Service) retryFrom(srcIdx int, fn func(src BlockSource) error) (err error) {
+	for attempt := 0; ; attempt++ {
+		if err = fn(s.sources[(srcIdx+attempt)%len(s.sources)]); err == nil || attempt >= s.cfg.MaxRetries {
+			return
+		}
+		time.Sleep(s.cfg.backoff(attempt))
+	}
+}
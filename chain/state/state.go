@@ -121,6 +121,42 @@ func (s *State) SetAuthInfo(addr []byte, pub *crypto.PublicKey) {
 	s.setBytes(assets.AUTH, addr, pub.Encode())
 }
 
+// validatorsAddr is system address of validators set
+var validatorsAddr = make([]byte, 20)
+
+// SetValidators sets validators set of PoA-consensus
+func (s *State) SetValidators(keys []*crypto.PublicKey) {
+	var buf []byte
+	for _, pub := range keys {
+		buf = append(buf, pub.Encode()...)
+	}
+	s.setBytes(assets.POA, validatorsAddr, buf)
+}
+
+// validatorsEpochAddr is system address of governance epoch of validators set
+var validatorsEpochAddr = append(make([]byte, 19), 1)
+
+// ValidatorsEpoch returns count of changes of validators set (see: txobj.ValidatorsUpd)
+func (s *State) ValidatorsEpoch() uint64 {
+	return uint64(s.Get(assets.POA, validatorsEpochAddr).Int64())
+}
+
+// SetValidatorsEpoch sets count of changes of validators set
+func (s *State) SetValidatorsEpoch(epoch uint64) {
+	s.Set(assets.POA, validatorsEpochAddr, bignum.NewInt(int64(epoch)), 0)
+}
+
+// Validators returns validators set of PoA-consensus
+func (s *State) Validators() (keys []*crypto.PublicKey) {
+	buf := s.getBytes(assets.POA, validatorsAddr)
+	for ; len(buf) >= crypto.KeySize*2; buf = buf[crypto.KeySize*2:] {
+		pub := new(crypto.PublicKey)
+		pub.Decode(buf[:crypto.KeySize*2])
+		keys = append(keys, pub)
+	}
+	return
+}
+
 func (s *State) AuthInfo(addr []byte) *crypto.PublicKey {
 	if buf := s.getBytes(assets.AUTH, addr); len(buf) == crypto.KeySize*2 {
 		var pub = new(crypto.PublicKey)
@@ -129,3 +165,54 @@ func (s *State) AuthInfo(addr []byte) *crypto.PublicKey {
 	}
 	return nil
 }
+
+// SetAssetInfo sets metadata of custom asset
+func (s *State) SetAssetInfo(asset []byte, inf *assets.Info) {
+	s.setBytes(assets.INFO, asset, inf.Encode())
+}
+
+// AssetInfo returns metadata of custom asset (nil if the asset is not defined)
+func (s *State) AssetInfo(asset []byte) *assets.Info {
+	if buf := s.getBytes(assets.INFO, asset); len(buf) > 0 {
+		inf := new(assets.Info)
+		if inf.Decode(buf) == nil {
+			return inf
+		}
+	}
+	return nil
+}
+
+// AssetSupply returns total supply of custom asset
+func (s *State) AssetSupply(asset []byte) bignum.Int {
+	return s.Get(assets.SUPPLY, asset)
+}
+
+// Seq returns the last sequence number of transactions of the sender (0 - sender has no sequenced transactions)
+func (s *State) Seq(addr []byte) uint64 {
+	return uint64(s.Get(assets.SEQ, addr).Int64())
+}
+
+// SetSeq sets the last sequence number of transactions of the sender
+func (s *State) SetSeq(addr []byte, seq uint64) {
+	s.Set(assets.SEQ, addr, bignum.NewInt(int64(seq)), 0)
+}
+
+// SetMultisigPolicy sets M-of-N policy of the account (nil - policy is removed)
+func (s *State) SetMultisigPolicy(addr []byte, p *crypto.MultisigPolicy) {
+	if p == nil {
+		s.Set(assets.MULTI, addr, bignum.Int{}, 0)
+		return
+	}
+	s.setBytes(assets.MULTI, addr, p.Encode())
+}
+
+// MultisigPolicy returns M-of-N policy of the account (nil if the account is not multisig)
+func (s *State) MultisigPolicy(addr []byte) *crypto.MultisigPolicy {
+	if buf := s.getBytes(assets.MULTI, addr); len(buf) > 0 {
+		p := new(crypto.MultisigPolicy)
+		if p.Decode(buf) == nil {
+			return p
+		}
+	}
+	return nil
+}
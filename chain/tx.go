@@ -19,6 +19,8 @@ import (
 
 const (
 	MaxTxDataSize = 4 * 1024
+
+	TxVersionSeq = 1 // Nonce of transaction is sequence number of sender transactions (see: Config.SeqHeight)
 )
 
 type Transaction struct {
@@ -41,9 +43,11 @@ type Transaction struct {
 	blockNum uint64            // block-num
 	blockIdx int               // tx-index in block
 	blockTs  int64             // block-timestamp in µsec
+	miner    *crypto.PublicKey // miner of block (is set for execution of tx in the block)
 	_obj     ITransaction      //
 	bc       BCContext         //
 	_users   map[uint64]string // cache of user nicks for current transaction
+	_sigKey  *crypto.PublicKey // public key that verified the signature (set by PreVerify)
 }
 
 func NewTx(
@@ -56,13 +60,26 @@ func NewTx(
 	if nonce == 0 {
 		nonce = NewNonce()
 	}
+	if sender == nil {
+		sender = prv.PublicKey()
+	}
+	tx := NewUnsignedTx(bc, sender, nonce, obj)
+	tx.Sig = prv.Sign(tx.Hash()) // set sender`s signature
+	return tx
+}
+
+// NewUnsignedTx returns transaction without signature.
+// Transaction of multisig account is signed by keys of the account offline (see: SignPartial, CombineSigs).
+func NewUnsignedTx(
+	bc BCContext,
+	sender *crypto.PublicKey,
+	nonce uint64,
+	obj ITransaction,
+) *Transaction {
 	cfg := DefaultConfig
 	if bc != nil {
 		cfg = bc.Config()
 	}
-	if sender == nil {
-		sender = prv.PublicKey()
-	}
 	tx := &Transaction{
 		Type:    model.TypeOf(obj), //
 		Version: 0,                 //
@@ -76,6 +93,22 @@ func NewTx(
 		_obj: obj,
 	}
 	obj.SetContext(tx)
+	return tx
+}
+
+// NewSeqTx returns sequenced transaction; seq must be the next sequence number of the sender (see: State.Seq)
+func NewSeqTx(
+	bc BCContext,
+	sender *crypto.PublicKey,
+	prv *crypto.PrivateKey,
+	seq uint64,
+	obj ITransaction,
+) *Transaction {
+	if sender == nil {
+		sender = prv.PublicKey()
+	}
+	tx := NewUnsignedTx(bc, sender, seq, obj)
+	tx.Version = TxVersionSeq
 	tx.Sig = prv.Sign(tx.Hash()) // set sender`s signature
 	return tx
 }
@@ -87,6 +120,10 @@ var (
 	ErrTxInvalidChainID   = errors.New("tx-verify-error: invalid chain-id")
 	ErrTxInvalidNetworkID = errors.New("tx-verify-error: invalid network-id")
 	ErrTxDataIsTooLong    = errors.New("tx-verify-error: tx is too long")
+	ErrTxSeqNotActive     = errors.New("tx-verify-error: sequenced transactions are not active")
+	ErrTxSeqRequired      = errors.New("tx-verify-error: sender requires sequenced transactions")
+	ErrTxSeqDuplicate     = errors.New("tx-verify-error: sequence number has been used")
+	ErrTxSeqGap           = errors.New("tx-verify-error: gap in sequence of sender transactions")
 )
 
 func (tx *Transaction) String() string {
@@ -171,6 +208,24 @@ func (tx *Transaction) SetBlockInfo(bc BCContext, blockNum uint64, blockTxIdx in
 	tx.bc, tx.blockNum, tx.blockIdx, tx.blockTs = bc, blockNum, blockTxIdx, blockTs
 }
 
+// SetBlockMiner sets miner of the block of transaction (receiver of tx-fee)
+func (tx *Transaction) SetBlockMiner(miner *crypto.PublicKey) {
+	tx.miner = miner
+}
+
+// BlockMiner returns miner of the block of transaction (nil - unknown)
+func (tx *Transaction) BlockMiner() *crypto.PublicKey {
+	return tx.miner
+}
+
+// Fee returns fee of the transaction in MDC (see: ITxFee)
+func (tx *Transaction) Fee() (fee bignum.Int) {
+	if obj, ok := tx.TxObject().(ITxFee); ok {
+		fee = obj.TxFee()
+	}
+	return
+}
+
 func (tx *Transaction) BCContext() BCContext {
 	if tx != nil {
 		return tx.bc
@@ -182,6 +237,29 @@ func (tx *Transaction) BlockNum() uint64 {
 	return tx.blockNum
 }
 
+// ExecBlockNum returns num of the block of transaction (num of the next block for unconfirmed tx)
+func (tx *Transaction) ExecBlockNum() uint64 {
+	if tx.blockNum == 0 && tx.bc != nil {
+		if h := tx.bc.LastBlockHeader(); h != nil {
+			return h.Num + 1
+		}
+	}
+	return tx.blockNum
+}
+
+// IsSequenced returns true if Nonce of the transaction is sequence number of sender transactions
+func (tx *Transaction) IsSequenced() bool {
+	return tx.Version == TxVersionSeq
+}
+
+// SenderSeq returns sequence number of sender transaction (0 - tx is not sequenced)
+func (tx *Transaction) SenderSeq() uint64 {
+	if tx.IsSequenced() {
+		return tx.Nonce
+	}
+	return 0
+}
+
 func (tx *Transaction) BlockIdx() int {
 	return tx.blockIdx
 }
@@ -269,6 +347,56 @@ func (tx *Transaction) Timestamp() time.Time {
 }
 
 func (tx *Transaction) Verify() error {
+	if err := tx.verifyData(); err != nil {
+		return err
+	}
+
+	//-- verify sender signature
+	if !tx.verifySig() {
+		return ErrInvalidTxSig
+	}
+	return tx.verifySeq(tx.txState())
+}
+
+// verifySeq verifies sequence number of the transaction by state of sender
+func (tx *Transaction) verifySeq(st *state.State) error {
+	if !tx.BCContext().Config().IsSeq(tx.ExecBlockNum()) {
+		if tx.IsSequenced() {
+			return ErrTxSeqNotActive
+		}
+		return nil
+	}
+	last := st.Seq(tx.SenderAddress())
+	switch {
+	case !tx.IsSequenced() && last > 0:
+		return ErrTxSeqRequired
+	case !tx.IsSequenced():
+		return nil
+	case tx.Nonce <= last:
+		return ErrTxSeqDuplicate
+	case tx.Nonce > last+1:
+		return ErrTxSeqGap
+	}
+	return nil
+}
+
+// PreVerify verifies transaction without access to the state (tx-data, tx-object and signature by tx-sender key).
+// It can be called concurrently for different transactions. The result of signature verification is cached and used by Verify.
+func (tx *Transaction) PreVerify() error {
+	if err := tx.verifyData(); err != nil {
+		return err
+	}
+	hash := tx.Hash()
+	if tx.Sender.Verify(hash, tx.Sig) {
+		tx._sigKey = tx.Sender
+	} else if masterKey := tx.BCContext().Config().MasterPubKey(); masterKey.Verify(hash, tx.Sig) {
+		tx._sigKey = masterKey
+	}
+	// invalid signature can be verified by the state (see senderAuth); so the error is returned by Verify
+	return nil
+}
+
+func (tx *Transaction) verifyData() error {
 	cfg := tx.BCContext().Config()
 
 	//-- verify transaction data
@@ -291,25 +419,26 @@ func (tx *Transaction) Verify() error {
 	if err != nil {
 		return err
 	}
-	if err := txObj.Verify(); err != nil {
-		return err
-	}
-
-	//-- verify sender signature
-	if !tx.verifySig() {
-		return ErrInvalidTxSig
-	}
-	return nil
+	return txObj.Verify()
 }
 
 func (tx *Transaction) verifySig() bool {
 	hash := tx.Hash()
-	if tx.senderAuth().Verify(hash, tx.Sig) {
+
+	// transaction of multisig account is verified by the policy only
+	if policy := tx.txState().MultisigPolicy(tx.SenderAddress()); policy != nil {
+		sigs, err := tx.MultiSigs()
+		return err == nil && policy.Verify(hash, sigs)
+	}
+
+	auth := tx.senderAuth()
+	if tx._sigKey != nil && tx._sigKey.Equal(auth) || auth.Verify(hash, tx.Sig) {
 		return true
 	}
 	// for genesis block can verify by masterKey
 	if tx.isGenesis() {
-		return tx.BCContext().Config().MasterPubKey().Verify(hash, tx.Sig)
+		masterKey := tx.BCContext().Config().MasterPubKey()
+		return tx._sigKey != nil && tx._sigKey.Equal(masterKey) || masterKey.Verify(hash, tx.Sig)
 	}
 	return false
 }
@@ -334,7 +463,11 @@ func (tx *Transaction) senderAuth() *crypto.PublicKey {
 func (tx *Transaction) Execute() (updates state.Values, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("tx.Execute-panic: %v", r)
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("tx.Execute-panic: %w", e)
+			} else {
+				err = fmt.Errorf("tx.Execute-panic: %v", r)
+			}
 		}
 	}()
 
@@ -345,6 +478,14 @@ func (tx *Transaction) Execute() (updates state.Values, err error) {
 
 	newState := tx.txState().NewSubState()
 
+	// sequenced transactions are executed in order of sequence numbers
+	if err = tx.verifySeq(newState); err != nil {
+		return
+	}
+	if tx.IsSequenced() {
+		newState.SetSeq(tx.SenderAddress(), tx.Nonce)
+	}
+
 	obj.Execute(newState)
 
 	updates = newState.Values()
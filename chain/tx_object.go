@@ -2,6 +2,7 @@ package chain
 
 import (
 	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/mediacoin-pro/core/common/bignum"
 	"github.com/mediacoin-pro/core/model"
 )
 
@@ -13,6 +14,11 @@ type ITransaction interface {
 	Execute(state *state.State)
 }
 
+// ITxFee is implemented by tx-objects with fee (see: Config.MinTxFee)
+type ITxFee interface {
+	TxFee() bignum.Int
+}
+
 func RegisterTxType(txType int, txObj ITransaction) error {
 	model.RegisterModel(txType, txObj)
 	return nil
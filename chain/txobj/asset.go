@@ -0,0 +1,325 @@
+package txobj
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/assets"
+	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/mediacoin-pro/core/common/bignum"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/common/hex"
+	"github.com/mediacoin-pro/core/common/json"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/mediacoin-pro/core/model"
+)
+
+// AssetDef defines new custom asset of the sender (issuer).
+// ID of the asset is assets.CustomID(issuerAddress, symbol); initial supply is credited to the issuer.
+type AssetDef struct {
+	Object
+	Symbol    string     //
+	Decimals  int        //
+	Supply    bignum.Int // initial supply
+	MaxSupply bignum.Int // 0 - unlimited
+	Mintable  bool       // issuer can mint new coins (see: Mint)
+	Burnable  bool       // issuer can burn own coins (see: Burn)
+
+	reserved1 []byte
+	reserved2 []byte
+}
+
+// Mint issues new coins of custom asset; must be signed by issuer of the asset
+type Mint struct {
+	Object
+	Asset  []byte     //
+	To     []byte     // address of recipient
+	Amount bignum.Int //
+
+	reserved1 []byte
+}
+
+// Burn destroys coins of custom asset from balance of issuer; must be signed by issuer of the asset
+type Burn struct {
+	Object
+	Asset  []byte     //
+	Amount bignum.Int //
+
+	reserved1 []byte
+}
+
+var (
+	_ = model.RegisterModel(model.TxAssetDef, &AssetDef{})
+	_ = model.RegisterModel(model.TxMint, &Mint{})
+	_ = model.RegisterModel(model.TxBurn, &Burn{})
+)
+
+const maxAssetDecimals = 18
+
+var isValidSymbol = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,15}$`).MatchString
+
+func NewAssetDef(
+	bc chain.BCContext,
+	sender *crypto.PublicKey,
+	prv *crypto.PrivateKey,
+	symbol string,
+	decimals int,
+	supply bignum.Int,
+	maxSupply bignum.Int,
+	mintable bool,
+	burnable bool,
+) *chain.Transaction {
+	return chain.NewTx(bc, sender, prv, 0, &AssetDef{
+		Symbol:    symbol,
+		Decimals:  decimals,
+		Supply:    supply,
+		MaxSupply: maxSupply,
+		Mintable:  mintable,
+		Burnable:  burnable,
+	})
+}
+
+func NewMint(
+	bc chain.BCContext,
+	sender *crypto.PublicKey,
+	prv *crypto.PrivateKey,
+	asset []byte,
+	to []byte,
+	amount bignum.Int,
+	nonce uint64,
+) *chain.Transaction {
+	return chain.NewTx(bc, sender, prv, nonce, &Mint{
+		Asset:  asset,
+		To:     to,
+		Amount: amount,
+	})
+}
+
+func NewBurn(
+	bc chain.BCContext,
+	sender *crypto.PublicKey,
+	prv *crypto.PrivateKey,
+	asset []byte,
+	amount bignum.Int,
+	nonce uint64,
+) *chain.Transaction {
+	return chain.NewTx(bc, sender, prv, nonce, &Burn{
+		Asset:  asset,
+		Amount: amount,
+	})
+}
+
+// AssetID returns ID of the defined asset
+func (a *AssetDef) AssetID() []byte {
+	return assets.CustomID(a.SenderAddress(), a.Symbol)
+}
+
+func (a *AssetDef) String() string {
+	return fmt.Sprintf("{AssetDef %s decimals:%d supply:%s max:%s}", a.Symbol, a.Decimals, a.Supply, a.MaxSupply)
+}
+
+func (a *AssetDef) Encode() []byte {
+	return bin.Encode(
+		0, // version
+
+		a.Symbol,
+		a.Decimals,
+		a.Supply,
+		a.MaxSupply,
+		a.Mintable,
+		a.Burnable,
+
+		a.reserved1,
+		a.reserved2,
+	)
+}
+
+func (a *AssetDef) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+
+		&a.Symbol,
+		&a.Decimals,
+		&a.Supply,
+		&a.MaxSupply,
+		&a.Mintable,
+		&a.Burnable,
+
+		&a.reserved1,
+		&a.reserved2,
+	)
+}
+
+func (a *AssetDef) Verify() error {
+	if !isValidSymbol(a.Symbol) || a.Symbol == "MDC" {
+		return ErrTxIncorrectSymbol
+	}
+	if a.Decimals < 0 || a.Decimals > maxAssetDecimals {
+		return ErrTxIncorrectDecimal
+	}
+	if a.Supply.Sign() < 0 || a.MaxSupply.Sign() < 0 {
+		return ErrTxIncorrectAmount
+	}
+	if a.MaxSupply.Sign() > 0 && a.Supply.Cmp(a.MaxSupply) > 0 {
+		return ErrTxMaxSupply
+	}
+	return nil
+}
+
+func (a *AssetDef) Execute(st *state.State) {
+	asset, issuer := a.AssetID(), a.SenderAddress()
+	if st.AssetInfo(asset) != nil {
+		st.Fail(ErrTxAssetExists)
+	}
+	st.SetAssetInfo(asset, &assets.Info{
+		Symbol:    a.Symbol,
+		Decimals:  a.Decimals,
+		Issuer:    issuer,
+		MaxSupply: a.MaxSupply,
+		Mintable:  a.Mintable,
+		Burnable:  a.Burnable,
+	})
+	st.Increment(assets.SUPPLY, asset, a.Supply, 0)
+	st.Increment(asset, issuer, a.Supply, 0)
+}
+
+func (a *AssetDef) MarshalJSON() ([]byte, error) {
+	return json.Object{
+		"asset":      hex.Encode(a.AssetID()),
+		"symbol":     a.Symbol,
+		"decimals":   a.Decimals,
+		"issuer":     a.SenderAddressStr(),
+		"supply":     a.Supply,
+		"max_supply": a.MaxSupply,
+		"mintable":   a.Mintable,
+		"burnable":   a.Burnable,
+	}.Bytes(), nil
+}
+
+// issuedAsset returns metadata of the custom asset issued by the sender
+func issuedAsset(st *state.State, asset, sender []byte) *assets.Info {
+	inf := st.AssetInfo(asset)
+	if inf == nil {
+		st.Fail(ErrTxAssetNotFound)
+	}
+	if !crypto.IsValidAddress(sender) || !bytes.Equal(inf.Issuer, sender) {
+		st.Fail(ErrTxNotIssuer)
+	}
+	return inf
+}
+
+func (m *Mint) String() string {
+	return fmt.Sprintf("{Mint %s %s to:%s}", assets.String(m.Asset), m.Amount, crypto.EncodeAddress(m.To))
+}
+
+func (m *Mint) Encode() []byte {
+	return bin.Encode(
+		0, // version
+
+		m.Asset,
+		m.To,
+		m.Amount,
+
+		m.reserved1,
+	)
+}
+
+func (m *Mint) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+
+		&m.Asset,
+		&m.To,
+		&m.Amount,
+
+		&m.reserved1,
+	)
+}
+
+func (m *Mint) Verify() error {
+	if !assets.IsCustom(m.Asset) {
+		return ErrTxIncorrectAsset
+	}
+	if m.Amount.Sign() <= 0 {
+		return ErrTxIncorrectAmount
+	}
+	if !crypto.IsValidAddress(m.To) {
+		return ErrTxIncorrectAddress
+	}
+	return nil
+}
+
+func (m *Mint) Execute(st *state.State) {
+	inf := issuedAsset(st, m.Asset, m.SenderAddress())
+	if !inf.Mintable {
+		st.Fail(ErrTxNotMintable)
+	}
+	supply := st.AssetSupply(m.Asset).Add(m.Amount)
+	if inf.MaxSupply.Sign() > 0 && supply.Cmp(inf.MaxSupply) > 0 {
+		st.Fail(ErrTxMaxSupply)
+	}
+	st.Set(assets.SUPPLY, m.Asset, supply, 0)
+	st.Increment(m.Asset, m.To, m.Amount, 0)
+}
+
+func (m *Mint) MarshalJSON() ([]byte, error) {
+	return json.Object{
+		"asset":  hex.Encode(m.Asset),
+		"to":     crypto.EncodeAddress(m.To),
+		"amount": m.Amount,
+	}.Bytes(), nil
+}
+
+func (b *Burn) String() string {
+	return fmt.Sprintf("{Burn %s %s}", assets.String(b.Asset), b.Amount)
+}
+
+func (b *Burn) Encode() []byte {
+	return bin.Encode(
+		0, // version
+
+		b.Asset,
+		b.Amount,
+
+		b.reserved1,
+	)
+}
+
+func (b *Burn) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+
+		&b.Asset,
+		&b.Amount,
+
+		&b.reserved1,
+	)
+}
+
+func (b *Burn) Verify() error {
+	if !assets.IsCustom(b.Asset) {
+		return ErrTxIncorrectAsset
+	}
+	if b.Amount.Sign() <= 0 {
+		return ErrTxIncorrectAmount
+	}
+	return nil
+}
+
+func (b *Burn) Execute(st *state.State) {
+	issuer := b.SenderAddress()
+	if !issuedAsset(st, b.Asset, issuer).Burnable {
+		st.Fail(ErrTxNotBurnable)
+	}
+	st.Decrement(b.Asset, issuer, b.Amount, 0)
+	st.Decrement(assets.SUPPLY, b.Asset, b.Amount, 0)
+}
+
+func (b *Burn) MarshalJSON() ([]byte, error) {
+	return json.Object{
+		"asset":  hex.Encode(b.Asset),
+		"amount": b.Amount,
+	}.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package txobj
+
+import (
+	"fmt"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/common/json"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/mediacoin-pro/core/model"
+)
+
+// Multisig sets M-of-N policy of the sender account; empty policy (Threshold=0, no keys) removes the policy.
+// Transactions of multisig account must be signed by Threshold keys of the policy (see: chain.Transaction.CombineSigs).
+type Multisig struct {
+	Object
+	Threshold int                 // min count of signatures (M)
+	Keys      []*crypto.PublicKey // keys of the account (N)
+
+	reserved1 []byte
+}
+
+var _ = chain.RegisterTxType(model.TxMultisig, &Multisig{})
+
+func NewMultisig(
+	bc chain.BCContext,
+	sender *crypto.PublicKey,
+	prv *crypto.PrivateKey,
+	threshold int,
+	keys []*crypto.PublicKey,
+	nonce uint64,
+) *chain.Transaction {
+	return chain.NewTx(bc, sender, prv, nonce, &Multisig{
+		Threshold: threshold,
+		Keys:      keys,
+	})
+}
+
+// Policy returns the policy of account (nil - the policy is removed)
+func (m *Multisig) Policy() *crypto.MultisigPolicy {
+	if m.Threshold == 0 && len(m.Keys) == 0 {
+		return nil
+	}
+	return &crypto.MultisigPolicy{
+		Threshold: m.Threshold,
+		Keys:      m.Keys,
+	}
+}
+
+func (m *Multisig) String() string {
+	return fmt.Sprintf("{Multisig %d of %d}", m.Threshold, len(m.Keys))
+}
+
+func (m *Multisig) Encode() []byte {
+	return bin.Encode(
+		0, // version
+
+		m.Threshold,
+		m.Keys,
+
+		m.reserved1,
+	)
+}
+
+func (m *Multisig) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+
+		&m.Threshold,
+		&m.Keys,
+
+		&m.reserved1,
+	)
+}
+
+func (m *Multisig) Verify() error {
+	if p := m.Policy(); p != nil && !p.IsValid() {
+		return ErrTxIncorrectPolicy
+	}
+	return nil
+}
+
+func (m *Multisig) Execute(st *state.State) {
+	st.SetMultisigPolicy(m.SenderAddress(), m.Policy())
+}
+
+func (m *Multisig) MarshalJSON() ([]byte, error) {
+	return json.Object{
+		"threshold": m.Threshold,
+		"keys":      m.Keys,
+	}.Bytes(), nil
+}
@@ -21,10 +21,16 @@ type Transfer struct {
 	Object
 	Outs    []*TransferOutput //
 	Comment []byte            // sender encrypted comment
+	Fee     bignum.Int        // fee in MDC (see: chain.Config.MinTxFee)
+
+	// not imported
+	decryptedComment string
 }
 
 var _ = model.RegisterModel(model.TxTransfer, &Transfer{})
 
+const maxCommentSize = 200
+
 var subscriptionRewardAddress = crypto.MustParseAddress("MDCB4B4kLg8f2W31a27QrbPLrc7c8nPMkyQ")
 
 type TransferOutput struct {
@@ -40,9 +46,16 @@ type TransferOutput struct {
 
 	// not imported
 	decryptedComment string
+	toPub            *crypto.PublicKey // recipient key for encryption of comment
 	tr               *Transfer
 }
 
+// encryptedComment is prefix of encrypted comments (plain comments are not prefixed)
+const encryptedComment = 0x01
+
+// encryptedCommentOverhead is size of prefix, nonce and tag (AES-GCM) of encrypted comment
+const encryptedCommentOverhead = 1 + 12 + 16
+
 func NewTransfer(
 	bc chain.BCContext,
 	sender *crypto.PublicKey,
@@ -51,19 +64,156 @@ func NewTransfer(
 	comment string,
 	nonce uint64,
 ) *chain.Transaction {
+	return NewTransferWithFee(bc, sender, prv, outs, comment, bignum.Int{}, nonce)
+}
 
-	//encComment := sender.EncryptRaw(bin.Uint64ToBytes(nonce), []byte(comment), nil)
-	encComment := []byte(comment)
+// NewTransferWithFee returns new transfer with fee in MDC (see: chain.Config.MinTxFee)
+func NewTransferWithFee(
+	bc chain.BCContext,
+	sender *crypto.PublicKey,
+	prv *crypto.PrivateKey,
+	outs []*TransferOutput,
+	comment string,
+	fee bignum.Int,
+	nonce uint64,
+) *chain.Transaction {
 
+	if nonce == 0 {
+		nonce = chain.NewNonce()
+	}
+	if sender == nil {
+		sender = prv.PublicKey()
+	}
 	tr := &Transfer{
 		Outs:    outs,
-		Comment: encComment,
+		Comment: []byte(comment),
+		Fee:     fee,
+	}
+	// comments are encrypted if transfer is signed by key of sender
+	if sender.Equal(prv.PublicKey()) {
+		cfg := chain.DefaultConfig
+		if bc != nil {
+			cfg = bc.Config()
+		}
+		tr.encryptComments(prv, cfg.ChainID, nonce)
 	}
 	defer tr.initOutputsContext()
 
 	return chain.NewTx(bc, sender, prv, nonce, tr)
 }
 
+// SetComment sets comment of the output for the recipient.
+// The comment is encrypted by NewTransfer with the key shared by sender and recipient.
+func (out *TransferOutput) SetComment(recipient *crypto.PublicKey, comment string) *TransferOutput {
+	out.To, out.toPub, out.Comment = recipient.Address(), recipient, []byte(comment)
+	return out
+}
+
+// SetRecipientKey sets public key of the recipient; it allows sender to decrypt comment of decoded output
+func (out *TransferOutput) SetRecipientKey(recipient *crypto.PublicKey) error {
+	if recipient == nil || !bytes.Equal(recipient.Address(), out.To) {
+		return ErrTxIncorrectAddress
+	}
+	out.toPub = recipient
+	return nil
+}
+
+func (tr *Transfer) encryptComments(prv *crypto.PrivateKey, chainID, nonce uint64) {
+	sender := prv.PublicKey().Address()
+	if len(tr.Comment) > 0 {
+		tr.Comment = encryptComment(prv, nil, commentAD(chainID, sender, nil, nonce, -1), tr.Comment)
+	}
+	for i, out := range tr.Outs {
+		if out.toPub != nil && len(out.Comment) > 0 {
+			out.Comment = encryptComment(prv, out.toPub, commentAD(chainID, sender, out.To, nonce, i), out.Comment)
+		}
+	}
+}
+
+// commentAD returns context of comment (authenticated data of encryption); outIdx = -1 for sender comment
+func commentAD(chainID uint64, sender, to []byte, nonce uint64, outIdx int) []byte {
+	return bin.Encode(chainID, sender, to, nonce, outIdx)
+}
+
+// encryptComment encrypts sender comment by personal key of sender
+// or comment of output by the key shared by sender and recipient
+func encryptComment(prv *crypto.PrivateKey, pub *crypto.PublicKey, ad, comment []byte) []byte {
+	return append([]byte{encryptedComment}, prv.EncryptRaw(comment, ad, pub)...)
+}
+
+func decryptComment(prv *crypto.PrivateKey, pub *crypto.PublicKey, ad, data []byte) (string, error) {
+	if !isEncryptedComment(data) {
+		return string(data), nil
+	}
+	text, err := prv.DecryptRaw(data[1:], ad, pub)
+	return string(text), err
+}
+
+func isEncryptedComment(data []byte) bool {
+	return len(data) > 0 && data[0] == encryptedComment
+}
+
+// isLongComment returns true if size of the comment (size of text for encrypted comment) is more than maxCommentSize
+func isLongComment(data []byte) bool {
+	if isEncryptedComment(data) {
+		return len(data) > maxCommentSize+encryptedCommentOverhead
+	}
+	return len(data) > maxCommentSize
+}
+
+// DecryptComment returns sender comment; encrypted comment can be decrypted by key of sender only
+func (tr *Transfer) DecryptComment(prv *crypto.PrivateKey) (string, error) {
+	if tr.decryptedComment != "" || len(tr.Comment) == 0 {
+		return tr.decryptedComment, nil
+	}
+	if isEncryptedComment(tr.Comment) && (prv == nil || !prv.PublicKey().Equal(tr.Sender())) {
+		return "", crypto.ErrDecrypt
+	}
+	tx := tr.tx
+	comment, err := decryptComment(prv, nil, commentAD(tx.ChainID, tx.SenderAddress(), nil, tx.Nonce, -1), tr.Comment)
+	if err == nil {
+		tr.decryptedComment = comment
+	}
+	return comment, err
+}
+
+// DecryptComment returns comment of the output; encrypted comment can be decrypted by key of recipient
+// or by key of sender if key of recipient is known (see: SetComment, SetRecipientKey)
+func (out *TransferOutput) DecryptComment(prv *crypto.PrivateKey) (string, error) {
+	if out.decryptedComment != "" || len(out.Comment) == 0 {
+		return out.decryptedComment, nil
+	}
+	pub := out.tr.Sender()
+	if prv != nil && out.toPub != nil && prv.PublicKey().Equal(pub) {
+		pub = out.toPub
+	} else if isEncryptedComment(out.Comment) && (prv == nil || !bytes.Equal(prv.PublicKey().Address(), out.To)) {
+		return "", crypto.ErrDecrypt
+	}
+	tx := out.tr.tx
+	comment, err := decryptComment(prv, pub, commentAD(tx.ChainID, tx.SenderAddress(), out.To, tx.Nonce, out.idx()), out.Comment)
+	if err == nil {
+		out.decryptedComment = comment
+	}
+	return comment, err
+}
+
+func (out *TransferOutput) idx() int {
+	for i, o := range out.tr.Outs {
+		if o == out {
+			return i
+		}
+	}
+	return -1
+}
+
+// DecryptData decrypts comments of the transfer available for the key; decrypted comments are shown by MarshalJSON
+func (tr *Transfer) DecryptData(prv *crypto.PrivateKey) {
+	tr.DecryptComment(prv)
+	for _, out := range tr.Outs {
+		out.DecryptComment(prv)
+	}
+}
+
 func NewSimpleTransfer(
 	bc chain.BCContext,
 	sender *crypto.PublicKey,
@@ -100,22 +250,40 @@ func (tr *Transfer) TotalAmount() (s bignum.Int) {
 	return
 }
 
+// TxFee returns fee of the transfer (see: chain.ITxFee)
+func (tr *Transfer) TxFee() bignum.Int {
+	return tr.Fee
+}
+
 func (tr *Transfer) Encode() []byte {
+	if tr.Fee.IsZero() { // transfer without fee is encoded by previous version
+		return bin.Encode(
+			0, // ver
+			tr.Comment,
+			tr.Outs,
+		)
+	}
 	return bin.Encode(
-		0, // ver
+		1, // ver
 		tr.Comment,
 		tr.Outs,
+		tr.Fee,
 	)
 }
 
-func (tr *Transfer) Decode(data []byte) error {
+func (tr *Transfer) Decode(data []byte) (err error) {
 	defer tr.initOutputsContext()
 
-	return bin.Decode(data,
-		new(int),
+	var ver int
+	buf := bin.NewBuffer(data)
+	if err = buf.ReadVar(
+		&ver,
 		&tr.Comment,
 		&tr.Outs,
-	)
+	); err == nil && ver >= 1 {
+		err = buf.ReadVar(&tr.Fee)
+	}
+	return
 }
 
 func (tr *Transfer) IsDonation() bool {
@@ -165,28 +333,56 @@ func (tr *Transfer) Verify() error {
 	if len(tr.Outs) == 0 {
 		return ErrTxEmptyOuts
 	}
-	if len([]byte(tr.Comment)) > 200 {
+	if isLongComment(tr.Comment) {
 		return ErrTxLongComment
 	}
 
+	if tr.Fee.Sign() < 0 {
+		return ErrTxIncorrectAmount
+	}
+	if tr.Fee.Cmp(tr.minFee()) < 0 {
+		return ErrTxLowFee
+	}
+
 	// check values; check sum of In and Out
 	for _, out := range tr.Outs {
+		if !assets.IsTransferable(out.Asset) {
+			return ErrTxIncorrectAsset
+		}
 		if out.Amount.Sign() <= 0 {
 			return ErrTxIncorrectAmount
 		}
 		if !crypto.IsValidAddress(out.To) {
 			return ErrTxIncorrectAddress
 		}
+		if isLongComment(out.Comment) {
+			return ErrTxLongComment
+		}
 	}
 
 	return nil
 }
 
+// minFee returns min fee of the transfer by the block of transaction (by the next block for unconfirmed tx)
+func (tr *Transfer) minFee() bignum.Int {
+	tx := tr.tx
+	if tx == nil || tx.BCContext() == nil {
+		return bignum.Int{}
+	}
+	return tx.BCContext().Config().MinTxFee(tx.ExecBlockNum(), len(tx.Data), len(tr.Outs))
+}
+
 func (tr *Transfer) Execute(st *state.State) {
 
 	senderAddr := tr.SenderAddress()
 
-	//st.Decrement(assets.MDC, senderAddr, t.Fee(), 0) // todo: ??? fee
+	// fee is credited to miner of the block or is burned
+	if tr.Fee.Sign() > 0 {
+		st.Decrement(assets.MDC, senderAddr, tr.Fee, 0)
+		if miner := tr.tx.BlockMiner(); miner != nil && !tr.tx.BCContext().Config().BurnFees {
+			st.Increment(assets.MDC, miner.Address(), tr.Fee, 0)
+		}
+	}
 
 	for _, out := range tr.Outs {
 		st.Decrement(out.Asset, senderAddr, out.Amount, out.Tag)
@@ -205,10 +401,12 @@ func (tr *Transfer) MarshalJSON() ([]byte, error) {
 		Outs       []*TransferOutput `json:"outs"`        //
 		RawComment []byte            `json:"raw_comment"` //
 		Comment    string            `json:"comment"`     //
+		Fee        bignum.Int        `json:"fee"`         //
 	}{
 		Outs:       tr.Outs,
 		RawComment: tr.Comment,
-		Comment:    string(tr.Comment), // todo: decoded comment
+		Comment:    commentStr(tr.Comment, tr.decryptedComment),
+		Fee:        tr.Fee,
 	})
 }
 
@@ -229,7 +427,7 @@ func (out *TransferOutput) MarshalJSON() ([]byte, error) {
 		ToChainID  uint64     `json:"to_chain_id"` //
 		ToNick     string     `json:"to_nick"`     //
 		RawComment []byte     `json:"raw_comment"` //
-		//Comment string  `json:"comment"` // todo: decoded comment
+		Comment    string     `json:"comment"`     //
 	}{
 		Asset:      hex.Encode(out.Asset),
 		Amount:     out.Amount,
@@ -239,24 +437,14 @@ func (out *TransferOutput) MarshalJSON() ([]byte, error) {
 		ToChainID:  out.ToChainID,
 		ToNick:     out.ToNick(),
 		RawComment: out.Comment,
+		Comment:    commentStr(out.Comment, out.decryptedComment),
 	})
 }
 
-//func (t *Transfer) DecryptData(prv *crypto.PrivateKey) {
-//	if prv == nil {
-//		return
-//	}
-//	pub := prv.PublicKey()
-//	if !pub.Equal(t.Sender()) {
-//		return
-//	}
-//
-//	tx := t.Tx()
-//	if tx == nil {
-//		return
-//	}
-//	for _, out := range t.Outs {
-//		prv.DecryptRaw(bin.Uint64ToBytes(tx.Nonce))
-//	}
-//
-//}
+// commentStr returns decrypted comment (see: DecryptData) or plain comment
+func commentStr(data []byte, decrypted string) string {
+	if decrypted != "" || isEncryptedComment(data) {
+		return decrypted
+	}
+	return string(data)
+}
@@ -17,6 +17,19 @@ var (
 	ErrTxLongComment      = errors.New("tx-Error: Comment is too long")
 	ErrTxEmptyOuts        = errors.New("tx-Error: Empty outputs")
 	ErrTxEmptyParam       = errors.New("tx-Error: Empty param")
+	ErrTxNoQuorum         = errors.New("tx-Error: Not enough signatures of validators")
+	ErrTxEmptyValidators  = errors.New("tx-Error: Empty validators set")
+	ErrTxIncorrectEpoch   = errors.New("tx-Error: Incorrect governance epoch")
+	ErrTxIncorrectSymbol  = errors.New("tx-Error: Incorrect asset symbol")
+	ErrTxIncorrectDecimal = errors.New("tx-Error: Incorrect asset decimals")
+	ErrTxAssetExists      = errors.New("tx-Error: Asset already exists")
+	ErrTxAssetNotFound    = errors.New("tx-Error: Asset not found")
+	ErrTxNotIssuer        = errors.New("tx-Error: Sender is not issuer of asset")
+	ErrTxNotMintable      = errors.New("tx-Error: Asset is not mintable")
+	ErrTxNotBurnable      = errors.New("tx-Error: Asset is not burnable")
+	ErrTxMaxSupply        = errors.New("tx-Error: Max supply of asset is exceeded")
+	ErrTxLowFee           = errors.New("tx-Error: Fee is too low")
+	ErrTxIncorrectPolicy  = errors.New("tx-Error: Incorrect multisig policy")
 )
 
 type Object struct {
@@ -0,0 +1,180 @@
+package txobj
+
+import (
+	"fmt"
+
+	"github.com/mediacoin-pro/core/chain"
+	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/mediacoin-pro/core/common/bin"
+	"github.com/mediacoin-pro/core/common/json"
+	"github.com/mediacoin-pro/core/crypto"
+	"github.com/mediacoin-pro/core/model"
+)
+
+// ValidatorsUpd is governance transaction that changes validators set of PoA-consensus.
+// The transaction must be signed by quorum of current validators.
+type ValidatorsUpd struct {
+	Object
+	Add    []*crypto.PublicKey // new validators
+	Remove []*crypto.PublicKey // removed validators
+	Sigs   []*ValidatorSig     // signatures of current validators
+	Epoch  uint64              // governance epoch of validators set (see: state.ValidatorsEpoch)
+
+	reserved1 []byte
+	reserved2 []byte
+}
+
+type ValidatorSig struct {
+	Validator *crypto.PublicKey // validator public key
+	Sig       []byte            // validator signature := validatorKey.Sign( ValidatorsUpdHash(...) )
+}
+
+var _ = chain.RegisterTxType(model.TxValidatorsUpd, &ValidatorsUpd{})
+
+func NewValidatorsUpd(
+	bc chain.BCContext,
+	sender *crypto.PublicKey,
+	prv *crypto.PrivateKey,
+	add []*crypto.PublicKey,
+	remove []*crypto.PublicKey,
+	sigs []*ValidatorSig,
+) *chain.Transaction {
+	return chain.NewTx(bc, sender, prv, 0, &ValidatorsUpd{
+		Add:    add,
+		Remove: remove,
+		Sigs:   sigs,
+		Epoch:  bc.State().ValidatorsEpoch(),
+	})
+}
+
+// ValidatorsUpdHash returns hash of changes of validators set that is signed by validators.
+// The hash depends on governance epoch that is incremented by each change,
+// so the signatures can not be replayed even if validators set returns to previous configuration.
+func ValidatorsUpdHash(chainID, epoch uint64, validators, add, remove []*crypto.PublicKey) []byte {
+	return bin.Hash256(chainID, epoch, validators, add, remove)
+}
+
+// SignValidatorsUpd returns signature of changes of validators set by the validator key
+func SignValidatorsUpd(bc chain.BCContext, add, remove []*crypto.PublicKey, validatorKey *crypto.PrivateKey) *ValidatorSig {
+	hash := ValidatorsUpdHash(bc.Config().ChainID, bc.State().ValidatorsEpoch(), chain.Validators(bc), add, remove)
+	return &ValidatorSig{
+		Validator: validatorKey.PublicKey(),
+		Sig:       validatorKey.Sign(hash),
+	}
+}
+
+func (u *ValidatorsUpd) String() string {
+	return fmt.Sprintf("{ValidatorsUPD add:%v remove:%v sigs:%d}", u.Add, u.Remove, len(u.Sigs))
+}
+
+func (u *ValidatorsUpd) Encode() []byte {
+	return bin.Encode(
+		0, // version
+
+		u.Add,
+		u.Remove,
+		u.Sigs,
+		u.Epoch,
+
+		u.reserved1,
+		u.reserved2,
+	)
+}
+
+func (u *ValidatorsUpd) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+
+		&u.Add,
+		&u.Remove,
+		&u.Sigs,
+		&u.Epoch,
+
+		&u.reserved1,
+		&u.reserved2,
+	)
+}
+
+func (s *ValidatorSig) Encode() []byte {
+	return bin.Encode(s.Validator, s.Sig)
+}
+
+func (s *ValidatorSig) Decode(data []byte) error {
+	return bin.Decode(data, &s.Validator, &s.Sig)
+}
+
+func (u *ValidatorsUpd) Verify() error {
+	if len(u.Add) == 0 && len(u.Remove) == 0 || len(u.Sigs) == 0 {
+		return ErrTxEmptyParam
+	}
+	for _, keys := range [][]*crypto.PublicKey{u.Add, u.Remove} {
+		for _, pub := range keys {
+			if pub.Empty() {
+				return ErrTxEmptyParam
+			}
+		}
+	}
+	for _, s := range u.Sigs {
+		if s == nil || s.Validator.Empty() || len(s.Sig) == 0 {
+			return ErrTxEmptyParam
+		}
+	}
+	return nil
+}
+
+func (u *ValidatorsUpd) Execute(st *state.State) {
+	cfg := u.ChainConfig()
+	validators := chain.StateValidators(st, cfg)
+	epoch := st.ValidatorsEpoch()
+	if u.Epoch != epoch {
+		st.Fail(ErrTxIncorrectEpoch)
+	}
+
+	// verify quorum of signatures of current validators
+	hash := ValidatorsUpdHash(cfg.ChainID, epoch, validators, u.Add, u.Remove)
+	signed := map[string]bool{}
+	for _, s := range u.Sigs {
+		if containsKey(validators, s.Validator) && s.Validator.Verify(hash, s.Sig) {
+			signed[string(s.Validator.Encode())] = true
+		}
+	}
+	if len(signed) < chain.ValidatorsQuorum(len(validators)) {
+		st.Fail(ErrTxNoQuorum)
+	}
+
+	// new validators set
+	var newSet []*crypto.PublicKey
+	for _, pub := range validators {
+		if !containsKey(u.Remove, pub) {
+			newSet = append(newSet, pub)
+		}
+	}
+	for _, pub := range u.Add {
+		if !containsKey(newSet, pub) {
+			newSet = append(newSet, pub)
+		}
+	}
+	if len(newSet) == 0 {
+		st.Fail(ErrTxEmptyValidators)
+	}
+	st.SetValidators(newSet)
+	st.SetValidatorsEpoch(epoch + 1)
+}
+
+func containsKey(keys []*crypto.PublicKey, pub *crypto.PublicKey) bool {
+	for _, k := range keys {
+		if k.Equal(pub) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *ValidatorsUpd) MarshalJSON() (data []byte, err error) {
+	return json.Object{
+		"add":    u.Add,
+		"remove": u.Remove,
+		"sigs":   len(u.Sigs),
+		"epoch":  u.Epoch,
+	}.Bytes(), nil
+}
@@ -0,0 +1,46 @@
+package chain
+
+import (
+	"github.com/mediacoin-pro/core/chain/state"
+	"github.com/mediacoin-pro/core/crypto"
+)
+
+// Validators returns current validators set of PoA-consensus (master key by default)
+func Validators(bc BCContext) []*crypto.PublicKey {
+	return StateValidators(bc.State(), bc.Config())
+}
+
+// StateValidators returns validators set of the state (master key by default)
+func StateValidators(st *state.State, cfg *Config) []*crypto.PublicKey {
+	if vv := st.Validators(); len(vv) > 0 {
+		return vv
+	}
+	return []*crypto.PublicKey{cfg.MasterPubKey()}
+}
+
+// ValidatorsQuorum returns min count of validators required for decision of validators set of size n (more than 2/3)
+func ValidatorsQuorum(n int) int {
+	return n*2/3 + 1
+}
+
+// ScheduledMiner returns public key of miner of the block.
+// Validators are scheduled by round-robin by block height.
+func ScheduledMiner(bc BCContext, blockNum uint64) *crypto.PublicKey {
+	cfg := bc.Config()
+	if !cfg.IsPoA(blockNum) {
+		return cfg.MasterPubKey()
+	}
+	vv := Validators(bc)
+	return vv[blockNum%uint64(len(vv))]
+}
+
+// VerifyMiner verifies that the block is signed by scheduled miner; bc must have the state of previous block
+func (b *BlockHeader) VerifyMiner(bc BCContext) error {
+	if !b.Miner.Equal(ScheduledMiner(bc, b.Num)) {
+		if !bc.Config().IsPoA(b.Num) {
+			return ErrInvalidMinerKey
+		}
+		return ErrNotScheduledMiner
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package goldb
+
+import (
+	"errors"
+	"fmt"
+)
+
+const tabSchemaVersion Entity = 0x7ffffffe
+
+var ErrNewerSchema = errors.New("goldb: database has been written by newer schema version")
+
+// Migration is step of upgrading of data schema
+type Migration struct {
+	Version int    // schema version after migration
+	Name    string // description of migration
+	Run     func(progress func(done, total int64)) error
+}
+
+type MigrationProgress struct {
+	Migration *Migration
+	Done      int64
+	Total     int64
+}
+
+type MigrateOptions struct {
+	DryRun   bool // returns pending migrations without applying
+	Progress func(MigrationProgress)
+}
+
+// SchemaVersion returns version of data schema (0 for database without version record)
+func (c *context) SchemaVersion() (v int, err error) {
+	_, err = c.GetVar(Key(tabSchemaVersion), &v)
+	return
+}
+
+// SetSchemaVersion sets version of data schema
+func (s *Storage) SetSchemaVersion(v int) error {
+	return s.PutVar(Key(tabSchemaVersion), v)
+}
+
+// Migrate applies migrations (ordered by version) with version greater than current schema version.
+// Schema version is updated after each successful migration.
+// It returns applied (or pending in dry-run mode) migrations.
+func (s *Storage) Migrate(migrations []*Migration, opts *MigrateOptions) (applied []*Migration, err error) {
+	if opts == nil {
+		opts = &MigrateOptions{}
+	}
+	cur, err := s.SchemaVersion()
+	if err != nil {
+		return
+	}
+	latest := 0
+	for i, m := range migrations {
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			return nil, fmt.Errorf("goldb: invalid order of migrations (version %d)", m.Version)
+		}
+		latest = m.Version
+	}
+	if cur > latest {
+		return nil, ErrNewerSchema
+	}
+	for _, m := range migrations {
+		if m.Version <= cur {
+			continue
+		}
+		if !opts.DryRun && m.Run != nil {
+			m := m
+			err = m.Run(func(done, total int64) {
+				if opts.Progress != nil {
+					opts.Progress(MigrationProgress{m, done, total})
+				}
+			})
+			if err != nil {
+				return applied, fmt.Errorf("goldb: migration %d (%s) error: %w", m.Version, m.Name, err)
+			}
+		}
+		if !opts.DryRun {
+			if err = s.SetSchemaVersion(m.Version); err != nil {
+				return
+			}
+		}
+		applied = append(applied, m)
+	}
+	return
+}
@@ -0,0 +1,74 @@
+package goldb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_Migrate(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+
+	var calls []int
+	var progress []MigrationProgress
+	migrations := []*Migration{
+		{Version: 1, Name: "m1"},
+		{Version: 2, Name: "m2", Run: func(fn func(done, total int64)) error {
+			calls = append(calls, 2)
+			fn(1, 2)
+			fn(2, 2)
+			return nil
+		}},
+		{Version: 5, Name: "m5", Run: func(fn func(done, total int64)) error {
+			calls = append(calls, 5)
+			return nil
+		}},
+	}
+
+	pending, err1 := store.Migrate(migrations, &MigrateOptions{DryRun: true})
+	ver1, _ := store.SchemaVersion()
+	applied, err2 := store.Migrate(migrations, &MigrateOptions{Progress: func(p MigrationProgress) {
+		progress = append(progress, p)
+	}})
+	ver2, _ := store.SchemaVersion()
+	applied2, err3 := store.Migrate(migrations, nil)
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.Equal(t, migrations, pending)
+	assert.Equal(t, 0, ver1)
+	assert.Equal(t, migrations, applied)
+	assert.Equal(t, 5, ver2)
+	assert.Empty(t, applied2)
+	assert.Equal(t, []int{2, 5}, calls)
+	assert.Equal(t, []MigrationProgress{{migrations[1], 1, 2}, {migrations[1], 2, 2}}, progress)
+}
+
+func TestStorage_Migrate_fail(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	errMigration := errors.New("migration error")
+
+	applied, err := store.Migrate([]*Migration{
+		{Version: 1, Name: "m1"},
+		{Version: 2, Name: "m2", Run: func(func(done, total int64)) error { return errMigration }},
+	}, nil)
+	ver, _ := store.SchemaVersion()
+
+	assert.ErrorIs(t, err, errMigration)
+	assert.Equal(t, 1, len(applied))
+	assert.Equal(t, 1, ver)
+}
+
+func TestStorage_Migrate_newerSchema(t *testing.T) {
+	store := newTestStorage()
+	defer store.Drop()
+	store.SetSchemaVersion(3)
+
+	_, err := store.Migrate([]*Migration{{Version: 1}, {Version: 2}}, nil)
+
+	assert.Equal(t, ErrNewerSchema, err)
+}
@@ -0,0 +1,44 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+)
+
+var ErrDecrypt = errors.New("crypto: message authentication failed")
+
+// EncryptRaw encrypts data by AES-256-GCM with the key shared by prv and pub (personal key of prv if pub is nil).
+// Random nonce is prepended to the result; ad is additional authenticated data (context of the message).
+func (prv *PrivateKey) EncryptRaw(data, ad []byte, pub *PublicKey) []byte {
+	aead := newAEAD(prv.sharedCipherKey(pub))
+	nonce := randBytes(aead.NonceSize())
+	return aead.Seal(nonce, nonce, data, ad)
+}
+
+// DecryptRaw decrypts and authenticates data encrypted by EncryptRaw with the same additional data.
+// Data encrypted by the key shared with pub can be decrypted by both prv and private key of pub.
+func (prv *PrivateKey) DecryptRaw(data, ad []byte, pub *PublicKey) ([]byte, error) {
+	aead := newAEAD(prv.sharedCipherKey(pub))
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, data := data[:aead.NonceSize()], data[aead.NonceSize():]
+	res, err := aead.Open(nil, nonce, data, ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return res, nil
+}
+
+func newAEAD(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(hash256(key))
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivateKey_EncryptRaw(t *testing.T) {
+	alice, bob, cat := NewPrivateKey(), NewPrivateKey(), NewPrivateKey()
+	ad, msg := []byte("context"), []byte("secret message")
+
+	enc := alice.EncryptRaw(msg, ad, bob.PublicKey())
+	dec1, err1 := bob.DecryptRaw(enc, ad, alice.PublicKey())
+	dec2, err2 := alice.DecryptRaw(enc, ad, bob.PublicKey())
+	_, err3 := cat.DecryptRaw(enc, ad, alice.PublicKey())
+	_, err4 := bob.DecryptRaw(enc, []byte("another context"), alice.PublicKey())
+	_, err5 := bob.DecryptRaw(enc[:5], ad, alice.PublicKey())
+
+	assert.NotContains(t, string(enc), string(msg))
+	assert.NoError(t, err1)
+	assert.Equal(t, msg, dec1)
+	assert.NoError(t, err2)
+	assert.Equal(t, msg, dec2)
+	assert.Equal(t, ErrDecrypt, err3)
+	assert.Equal(t, ErrDecrypt, err4)
+	assert.Equal(t, ErrDecrypt, err5)
+}
+
+func TestPrivateKey_EncryptRaw_randomNonce(t *testing.T) {
+	alice, bob := NewPrivateKey(), NewPrivateKey()
+	ad, msg := []byte("context"), []byte("secret message")
+
+	// the same message in the same context is encrypted by different nonces
+	enc1 := alice.EncryptRaw(msg, ad, bob.PublicKey())
+	enc2 := bob.EncryptRaw(msg, ad, alice.PublicKey())
+
+	assert.NotEqual(t, enc1, enc2)
+}
+
+func TestPrivateKey_EncryptRaw_personal(t *testing.T) {
+	alice, bob := NewPrivateKey(), NewPrivateKey()
+	ad, msg := []byte("context"), []byte("secret message")
+
+	enc := alice.EncryptRaw(msg, ad, nil)
+	enc[len(enc)-1] ^= 1
+	_, err1 := alice.DecryptRaw(enc, ad, nil)
+	enc[len(enc)-1] ^= 1
+	dec, err2 := alice.DecryptRaw(enc, ad, nil)
+	_, err3 := bob.DecryptRaw(enc, ad, nil)
+
+	assert.Equal(t, ErrDecrypt, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, msg, dec)
+	assert.Equal(t, ErrDecrypt, err3)
+}
@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"errors"
+
+	"github.com/mediacoin-pro/core/common/bin"
+)
+
+const MaxMultisigKeys = 16
+
+var ErrInvalidMultiSig = errors.New("crypto: invalid multi-signature")
+
+// MultisigPolicy is M-of-N policy of multi-signature account
+type MultisigPolicy struct {
+	Threshold int          // min count of signatures of different keys (M)
+	Keys      []*PublicKey // keys of the account (N)
+}
+
+// KeySig is signature of data by one of keys of multisig account
+type KeySig struct {
+	Key *PublicKey
+	Sig []byte
+}
+
+func (p *MultisigPolicy) Encode() []byte {
+	return bin.Encode(
+		0, // version
+		p.Threshold,
+		p.Keys,
+	)
+}
+
+func (p *MultisigPolicy) Decode(data []byte) error {
+	return bin.Decode(data,
+		new(int), // version
+		&p.Threshold,
+		&p.Keys,
+	)
+}
+
+// IsValid returns true if the policy has 1 <= Threshold <= len(Keys) <= MaxMultisigKeys different keys
+func (p *MultisigPolicy) IsValid() bool {
+	if p.Threshold < 1 || p.Threshold > len(p.Keys) || len(p.Keys) > MaxMultisigKeys {
+		return false
+	}
+	for i, pub := range p.Keys {
+		if pub == nil || pub.Empty() {
+			return false
+		}
+		if hasKey(p.Keys[:i], pub) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasKey returns true if the key is one of keys of the policy
+func (p *MultisigPolicy) HasKey(pub *PublicKey) bool {
+	return hasKey(p.Keys, pub)
+}
+
+// Verify returns true if data is signed by at least Threshold different keys of the policy
+func (p *MultisigPolicy) Verify(data []byte, sigs []*KeySig) bool {
+	var signed []*PublicKey
+	for _, s := range sigs {
+		if s == nil || !p.HasKey(s.Key) || hasKey(signed, s.Key) {
+			continue
+		}
+		if s.Key.Verify(data, s.Sig) {
+			signed = append(signed, s.Key)
+		}
+	}
+	return len(signed) >= p.Threshold
+}
+
+func hasKey(keys []*PublicKey, pub *PublicKey) bool {
+	for _, k := range keys {
+		if k.Equal(pub) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *KeySig) Encode() []byte {
+	return bin.Encode(s.Key, s.Sig)
+}
+
+func (s *KeySig) Decode(data []byte) error {
+	r := bin.NewBuffer(data)
+	key, err := readMultiSigBytes(r)
+	if err != nil {
+		return err
+	}
+	if s.Sig, err = readMultiSigBytes(r); err != nil {
+		return err
+	}
+	s.Key = new(PublicKey)
+	return s.Key.Decode(key)
+}
+
+// EncodeMultiSig returns signatures of keys of multisig account as one signature
+func EncodeMultiSig(sigs []*KeySig) []byte {
+	return bin.Encode(sigs)
+}
+
+// DecodeMultiSig decodes signatures encoded by EncodeMultiSig.
+// Signature of transaction is not trusted, so sizes of the data are limited by MaxMultisigKeys and size of the signature.
+func DecodeMultiSig(data []byte) ([]*KeySig, error) {
+	r := bin.NewBuffer(data)
+	n, err := r.ReadVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > MaxMultisigKeys {
+		return nil, ErrInvalidMultiSig
+	}
+	sigs := make([]*KeySig, n)
+	for i := range sigs {
+		bb, err := readMultiSigBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = new(KeySig)
+		if err = sigs[i].Decode(bb); err != nil {
+			return nil, err
+		}
+	}
+	return sigs, nil
+}
+
+// readMultiSigBytes reads length-prefixed bytes; the length is limited by the rest of data
+func readMultiSigBytes(r *bin.Buffer) ([]byte, error) {
+	n, err := r.ReadVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > r.Buffer().Len() {
+		return nil, ErrInvalidMultiSig
+	}
+	return r.Buffer().Next(n), nil
+}
@@ -0,0 +1,46 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultisigPolicy_Verify(t *testing.T) {
+	k1, k2, k3 := NewPrivateKey(), NewPrivateKey(), NewPrivateKey()
+	p := &MultisigPolicy{2, []*PublicKey{k1.PublicKey(), k2.PublicKey()}}
+	data := []byte("data")
+	sig := func(prv *PrivateKey) *KeySig {
+		return &KeySig{prv.PublicKey(), prv.Sign(data)}
+	}
+
+	assert.True(t, p.IsValid())
+	assert.True(t, p.Verify(data, []*KeySig{sig(k1), sig(k2)}))
+	assert.True(t, p.Verify(data, []*KeySig{sig(k3), sig(k2), sig(k1)}))
+	assert.False(t, p.Verify(data, []*KeySig{sig(k1), sig(k1)}))
+	assert.False(t, p.Verify(data, []*KeySig{sig(k1), sig(k3)}))
+	assert.False(t, p.Verify([]byte("another data"), []*KeySig{sig(k1), sig(k2)}))
+}
+
+func TestMultisigPolicy_Encode(t *testing.T) {
+	p := &MultisigPolicy{1, []*PublicKey{NewPrivateKey().PublicKey(), NewPrivateKey().PublicKey()}}
+	sigs := []*KeySig{{p.Keys[0], []byte{1, 2, 3}}}
+
+	var p1 MultisigPolicy
+	err1 := p1.Decode(p.Encode())
+	sigs1, err2 := DecodeMultiSig(EncodeMultiSig(sigs))
+
+	assert.NoError(t, err1)
+	assert.Equal(t, p.Encode(), p1.Encode())
+	assert.NoError(t, err2)
+	assert.Equal(t, sigs[0].Sig, sigs1[0].Sig)
+	assert.True(t, sigs[0].Key.Equal(sigs1[0].Key))
+}
+
+func TestDecodeMultiSig_invalid(t *testing.T) {
+	sig := NewPrivateKey().Sign([]byte("data"))
+
+	_, err := DecodeMultiSig(sig)
+
+	assert.Error(t, err)
+}
@@ -92,8 +92,35 @@ func (t *Tree) Put(key, value []byte) (err error) {
 	return
 }
 
-func (t *Tree) Delete(key []byte) error {
-	return t.Put(key, nil)
+// Delete removes key from the tree.
+// The tree is restructured so its root is equal to the root of the tree that has never contained the key.
+func (t *Tree) Delete(key []byte) (err error) {
+	t.puts = map[string]*node{}
+	defer func() {
+		t.puts = nil
+	}()
+
+	root, err := t.remove(make([]byte, 0, 10), key)
+	if err == errKeyNotFound {
+		return nil // nothing to delete
+	} else if err != nil {
+		return
+	}
+
+	// save to db; removed nodes are saved as empty data
+	for path, nd := range t.puts {
+		var data []byte
+		if nd != nil {
+			data = nd.encode()
+		}
+		if err = t.db.Put([]byte(path), data); err != nil {
+			return
+		}
+	}
+
+	// success
+	t.root = root
+	return
 }
 
 func (t *Tree) Get(key []byte) (value []byte, err error) {
@@ -193,6 +220,53 @@ func (t *Tree) put(path, key, value []byte) (newHash []byte, err error) {
 	return nd.hash(), err
 }
 
+func (t *Tree) remove(path, key []byte) (newHash []byte, err error) {
+	nd, err := t.getNode(path)
+	if err != nil {
+		return
+	}
+	if nd == nil {
+		return nil, errKeyNotFound
+	}
+	if nd.key != nil { // leaf
+		if !bytes.Equal(nd.key, key) {
+			return nil, errKeyNotFound
+		}
+		t.puts[string(path)] = nil
+		return nil, nil
+	}
+	i := idx(key, len(path))
+	if nd.hashes[i], err = t.remove(append(path, i), key); err != nil {
+		return
+	}
+
+	// collapse the branch when the only one leaf is left
+	var n, j int
+	for k, h := range nd.hashes {
+		if h != nil {
+			n, j = n+1, k
+		}
+	}
+	if n == 0 {
+		t.puts[string(path)] = nil
+		return nil, nil
+	}
+	if n == 1 {
+		childPath := append(path, uint8(j))
+		child, err := t.getNode(childPath)
+		if err != nil {
+			return nil, err
+		}
+		if child != nil && child.key != nil {
+			t.puts[string(childPath)] = nil
+			t.puts[string(path)] = child
+			return child.hash(), nil
+		}
+	}
+	t.puts[string(path)] = nd
+	return nd.hash(), nil
+}
+
 func (t *Tree) proof(path, key []byte) (value, proof []byte, err error) {
 	nd, err := t.getNode(path)
 	if err != nil {
@@ -188,6 +188,53 @@ func TestTree_AppendingProof(t *testing.T) {
 	}
 }
 
+func TestTree_Delete(t *testing.T) {
+	a := NewTree(nil)
+	b := NewTree(nil)
+	vals := testValues(1000)
+	for k, v := range vals {
+		a.PutVar(k, v)
+		if k%3 != 0 {
+			b.PutVar(k, v)
+		}
+	}
+	for k := range vals {
+		if k%3 == 0 {
+			err := a.Delete(encode(k))
+			assert.NoError(t, err)
+		}
+	}
+
+	aRoot, _ := a.Root()
+	bRoot, _ := b.Root()
+	assert.Equal(t, bRoot, aRoot)
+
+	for k, v := range vals {
+		val, err := a.Get(encode(k))
+		if k%3 == 0 {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, v, val)
+		}
+	}
+}
+
+func TestTree_Delete_all(t *testing.T) {
+	a := NewTree(nil)
+	for k, v := range testValues(100) {
+		a.PutVar(k, v)
+	}
+	for k := range testValues(100) {
+		a.Delete(encode(k))
+	}
+	err := a.Delete(encode(100500)) // not existed key
+
+	root, _ := a.Root()
+	assert.NoError(t, err)
+	assert.Equal(t, "", hex.EncodeToString(root))
+}
+
 func testValues(n int) map[int][]byte {
 	v := make(map[int][]byte, n)
 	for i := 0; i < n; i++ {
@@ -14,11 +14,26 @@ const (
 	TxUser     = 3
 	TxUserUpd  = 4
 
+	TxValidatorsUpd = 5
+
+	TxAssetDef = 6
+	TxMint     = 7
+	TxBurn     = 8
+
+	TxMultisig = 9
+
 	ObjDocument = 10
 	ObjFile     = 11
 	ObjLink     = 12
 	ObjCounter  = 13
 	ObjNode     = 14
+
+	// p2p messages
+	MsgHandshake = 30
+	MsgTxs       = 31
+	MsgNewBlock  = 32
+	MsgGetBlocks = 33
+	MsgBlocks    = 34
 )
 
 // Usage: